@@ -35,12 +35,49 @@ func BenchmarkBencodeMarshal(b *testing.B) {
 	}
 }
 
-func BenchmarkBencodeUnmarshal(b *testing.B) {
+func BenchmarkAppendMarshal(b *testing.B) {
 	b.ReportAllocs()
+	var buf []byte
 	for n := 0; n < b.N; n++ {
-		result, err := Decode(bytes.NewReader(unmarshalTestData))
+		var err error
+		buf, err = AppendMarshal(buf[:0], marshalTestData)
 		if err != nil {
-			b.Errorf("Decode returned %+v, %v", result, err)
+			b.Errorf("AppendMarshal returned %v", err)
+		}
+	}
+}
+
+func BenchmarkBencodeUnmarshal(b *testing.B) {
+	b.Run("Decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			result, err := Decode(bytes.NewReader(unmarshalTestData))
+			if err != nil {
+				b.Errorf("Decode returned %+v, %v", result, err)
+			}
+		}
+	})
+	b.Run("DecodeBytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			result, err := DecodeBytes(unmarshalTestData)
+			if err != nil {
+				b.Errorf("DecodeBytes returned %+v, %v", result, err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecoderToken(b *testing.B) {
+	stream := bytes.Repeat(unmarshalTestData, 100)
+	b.SetBytes(int64(len(stream)))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		d := NewDecoder(bytes.NewReader(stream))
+		for {
+			if _, err := d.Token(); err != nil {
+				break
+			}
 		}
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -36,6 +38,13 @@ func check(expected string, data any) (err error) {
 	if err = checkFuzzyEqual(data, val); err != nil {
 		return
 	}
+	if val, err = DecodeBytes([]byte(expected)); err != nil {
+		err = errors.New(fmt.Sprint("Failed decoding bytes ", expected, " ", err))
+		return
+	}
+	if err = checkFuzzyEqual(data, val); err != nil {
+		return
+	}
 	return
 }
 
@@ -181,7 +190,14 @@ func checkUnmarshal(expected string, data any) (err error) {
 	dataValue := reflect.ValueOf(data)
 	newOne := reflect.New(reflect.TypeOf(data))
 	buf := bytes.NewBufferString(expected)
-	if err = unmarshalValue(buf, newOne); err != nil {
+	if err = unmarshalValue(buf, newOne, &decodeOptions{}); err != nil {
+		return
+	}
+	if err = checkFuzzyEqualValue(dataValue, newOne.Elem()); err != nil {
+		return
+	}
+	newOne = reflect.New(reflect.TypeOf(data))
+	if err = UnmarshalBytes([]byte(expected), newOne.Interface()); err != nil {
 		return
 	}
 	if err = checkFuzzyEqualValue(dataValue, newOne.Elem()); err != nil {
@@ -250,10 +266,6 @@ var (
 		SVPair{"i-7.5e", -7},
 		SVPair{"i7.574E+2e", 757},
 		SVPair{"i-7.574E+2e", -757},
-		// This test is architecture specific.
-		// See https://stackoverflow.com/a/70259392
-		// SVPair{"i7.574E+20e", -9223372036854775808},
-		SVPair{"i-7.574E+20e", -9223372036854775808},
 		SVPair{"i7.574E-2e", 0},
 		SVPair{"i-7.574E-2e", 0},
 		SVPair{"i7.574E-20e", 0},
@@ -370,3 +382,558 @@ func TestMarshalDifferentTypes(t *testing.T) {
 		t.Fatalf("Incorrectly encoded byte array, got %s", buf.String())
 	}
 }
+
+// peerID marshals itself with a value receiver and unmarshals with a
+// pointer receiver.
+type peerID [4]byte
+
+func (p peerID) MarshalBencode() ([]byte, error) {
+	return []byte("4:" + string(p[:])), nil
+}
+
+func (p *peerID) UnmarshalBencode(data []byte) error {
+	var s string
+	if err := Unmarshal(bytes.NewReader(data), &s); err != nil {
+		return err
+	}
+	if len(s) != len(p) {
+		return fmt.Errorf("bad peer id length %d", len(s))
+	}
+	copy(p[:], s)
+	return nil
+}
+
+// hexInt uses pointer receivers for both methods.
+type hexInt int
+
+func (h *hexInt) MarshalBencode() ([]byte, error) {
+	s := fmt.Sprintf("%x", int(*h))
+	return []byte(fmt.Sprintf("%d:%s", len(s), s)), nil
+}
+
+func (h *hexInt) UnmarshalBencode(data []byte) error {
+	var s string
+	if err := Unmarshal(bytes.NewReader(data), &s); err != nil {
+		return err
+	}
+	_, err := fmt.Sscanf(s, "%x", (*int)(h))
+	return err
+}
+
+type badMarshaler struct{}
+
+func (badMarshaler) MarshalBencode() ([]byte, error) { return []byte("i1ei2e"), nil }
+
+type withMarshalers struct {
+	ID    peerID
+	Peers []peerID
+	Count hexInt
+	Ptr   *hexInt
+	ByKey map[string]hexInt
+}
+
+func TestMarshalerRoundTrip(t *testing.T) {
+	count := hexInt(255)
+	in := withMarshalers{
+		ID:    peerID{'a', 'b', 'c', 'd'},
+		Peers: []peerID{{'w', 'x', 'y', 'z'}},
+		Count: 16,
+		Ptr:   &count,
+		ByKey: map[string]hexInt{"k": 26},
+	}
+	var buf bytes.Buffer
+	if err := Marshal(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	const want = "d5:ByKeyd1:k2:1ae5:Count2:102:ID4:abcd5:Peersl4:wxyze3:Ptr2:ffe"
+	if buf.String() != want {
+		t.Fatalf("Marshal = %q, want %q", buf.String(), want)
+	}
+
+	var out withMarshalers
+	if err := Unmarshal(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != in.ID || len(out.Peers) != 1 || out.Peers[0] != in.Peers[0] ||
+		out.Count != 16 || out.Ptr == nil || *out.Ptr != 255 || out.ByKey["k"] != 26 {
+		t.Fatalf("Unmarshal = %+v", out)
+	}
+}
+
+func TestMarshalerInvalidOutput(t *testing.T) {
+	var buf bytes.Buffer
+	err := Marshal(&buf, []any{badMarshaler{}})
+	var merr *MarshalerError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Marshal returned %v, want a MarshalerError", err)
+	}
+}
+
+func TestUnmarshalerError(t *testing.T) {
+	var out withMarshalers
+	err := Unmarshal(bytes.NewBufferString("d2:ID3:abce"), &out)
+	if err == nil || err.Error() != "bad peer id length 3" {
+		t.Fatalf("Unmarshal returned %v", err)
+	}
+}
+
+type bitfield []byte
+
+type byteFields struct {
+	Pieces   []byte   "pieces"
+	InfoHash [4]byte  "info hash"
+	Have     bitfield "have"
+	Nodes    map[string][2]byte
+}
+
+func TestUnmarshalByteFields(t *testing.T) {
+	const input = "d5:Nodesd1:n2:\x00\x01e4:have2:\xf0\x019:info hash4:abcd6:pieces3:\x00\x01\x02e"
+	var bf byteFields
+	if err := Unmarshal(bytes.NewBufferString(input), &bf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bf.Pieces, []byte{0, 1, 2}) {
+		t.Errorf("Pieces = %v", bf.Pieces)
+	}
+	if bf.InfoHash != [4]byte{'a', 'b', 'c', 'd'} {
+		t.Errorf("InfoHash = %v", bf.InfoHash)
+	}
+	if !bytes.Equal(bf.Have, []byte{0xf0, 1}) {
+		t.Errorf("Have = %v", bf.Have)
+	}
+	if bf.Nodes["n"] != [2]byte{0, 1} {
+		t.Errorf("Nodes = %v", bf.Nodes)
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, bf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Fatalf("Marshal = %q, want %q", buf.String(), input)
+	}
+}
+
+func TestUnmarshalByteArrayLength(t *testing.T) {
+	var bf byteFields
+	err := Unmarshal(bytes.NewBufferString("d9:info hash3:abce"), &bf)
+	if err == nil {
+		t.Fatal("expected an error unmarshalling a 3-byte string into [4]byte")
+	}
+}
+
+// myByte is a named byte type. Slices and arrays of it are encoded as
+// strings, like those of byte.
+type myByte byte
+
+type namedByteFields struct {
+	Array [4]myByte `bencode:"array"`
+	Slice []myByte  `bencode:"slice"`
+}
+
+func TestMarshalNamedByteArray(t *testing.T) {
+	const want = "d5:array4:abcd5:slice2:hie"
+	nb := namedByteFields{[4]myByte{'a', 'b', 'c', 'd'}, []myByte("hi")}
+	// Marshal sees an addressable array through a pointer, and a copy
+	// otherwise.
+	for _, v := range []interface{}{nb, &nb} {
+		var buf bytes.Buffer
+		if err := Marshal(&buf, v); err != nil || buf.String() != want {
+			t.Errorf("Marshal(%T) = %q, %v, want %q", v, buf.String(), err, want)
+		}
+	}
+	if err := checkMarshal("d1:k2:hie", map[string][2]myByte{"k": {'h', 'i'}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnmarshalNamedByteArray(t *testing.T) {
+	const input = "d5:array4:abcd5:slice2:hie"
+	want := namedByteFields{[4]myByte{'a', 'b', 'c', 'd'}, []myByte("hi")}
+	var fromReader, fromBytes namedByteFields
+	if err := Unmarshal(bytes.NewBufferString(input), &fromReader); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalBytes([]byte(input), &fromBytes); err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []namedByteFields{fromReader, fromBytes} {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+	var a [4]myByte
+	if err := UnmarshalBytes([]byte("4:abcd"), &a); err != nil || a != want.Array {
+		t.Errorf("UnmarshalBytes into [4]myByte = %v, %v", a, err)
+	}
+}
+
+type boolFields struct {
+	Private    bool            "private"
+	UploadOnly bool            "upload_only"
+	Flags      map[string]bool "flags"
+}
+
+func TestMarshalBool(t *testing.T) {
+	if err := checkMarshal("d5:flagsd2:roi1ee7:privatei1e11:upload_onlyi0ee",
+		boolFields{Private: true, Flags: map[string]bool{"ro": true}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkMarshal("li0ei1ee", []bool{false, true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnmarshalBool(t *testing.T) {
+	var bf boolFields
+	input := "d5:flagsd2:roi1e4:seedi0ee7:privatei1e11:upload_onlyi0ee"
+	if err := Unmarshal(bytes.NewBufferString(input), &bf); err != nil {
+		t.Fatal(err)
+	}
+	if !bf.Private || bf.UploadOnly || !bf.Flags["ro"] || bf.Flags["seed"] {
+		t.Fatalf("got %+v", bf)
+	}
+
+	bf = boolFields{}
+	if err := Unmarshal(bytes.NewBufferString("d7:privatei2ee"), &bf); err != nil {
+		t.Fatal(err)
+	}
+	if !bf.Private {
+		t.Fatal("i2e should unmarshal as true outside strict mode")
+	}
+
+	d := NewDecoder(bytes.NewBufferString("d7:privatei2ee"))
+	d.SetStrict(true)
+	if err := d.DecodeInto(&bf); err == nil {
+		t.Fatal("expected an error unmarshalling i2e into bool in strict mode")
+	}
+}
+
+type pointerInfo struct {
+	Name   string "name"
+	Length *int64 "length"
+}
+
+type pointerTorrent struct {
+	Announce *string      "announce"
+	Info     *pointerInfo "info"
+	Nodes    []*string    "nodes"
+}
+
+func TestMarshalPointers(t *testing.T) {
+	length := int64(12)
+	node := "n1"
+	tests := []SVPair{
+		{"d4:infod6:lengthi12e4:name1:ae5:nodesl2:n1ee",
+			&pointerTorrent{Info: &pointerInfo{"a", &length}, Nodes: []*string{&node}}},
+		{"d4:infod4:name1:ae5:nodeslee", pointerTorrent{Info: &pointerInfo{Name: "a"}}},
+		{"d5:nodeslee", &pointerTorrent{}},
+		{"i12e", &length},
+	}
+	for _, tt := range tests {
+		if err := checkMarshal(tt.s, tt.v); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, []*string{nil}); err == nil {
+		t.Error("expected an error marshalling a nil pointer in a list")
+	}
+}
+
+type cyclic struct {
+	Name string
+	Next *cyclic
+}
+
+func TestEncoderPointerCycle(t *testing.T) {
+	c := &cyclic{Name: "a"}
+	c.Next = &cyclic{Name: "b", Next: c}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetCycleDetection(true)
+	if err := enc.Encode(c); err == nil {
+		t.Fatal("expected an error encoding a pointer cycle")
+	}
+	c.Next.Next = nil
+	if err := enc.Encode(c); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "d4:Name1:a4:Nextd4:Name1:bee" {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+type Embedded struct {
+	A      int
+	Shadow string "shadow"
+	hidden int
+}
+
+type embeddedPtr struct {
+	C int "c"
+}
+
+type embeddingStruct struct {
+	Embedded
+	*embeddedPtr
+	Shadow string "shadow"
+	secret string
+	Tagged Embedded "tagged"
+}
+
+type duplicateKeys struct {
+	A int `bencode:"x"`
+	B int `bencode:"x"`
+	C int
+}
+
+func TestMarshalEmbedded(t *testing.T) {
+	tests := []SVPair{
+		{"d1:Ai1e1:ci3e6:shadow5:outer6:taggedd1:Ai0e6:shadow0:ee",
+			embeddingStruct{Embedded: Embedded{1, "inner", 2}, embeddedPtr: &embeddedPtr{3}, Shadow: "outer", secret: "s"}},
+		{"d1:Ai1e6:shadow0:6:taggedd1:Ai0e6:shadow0:ee",
+			embeddingStruct{Embedded: Embedded{A: 1}}},
+		{"d1:Ci3ee", duplicateKeys{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		if err := checkMarshal(tt.s, tt.v); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestUnmarshalEmbedded(t *testing.T) {
+	const input = "d1:ai1e1:ci3e6:hiddeni9e6:secret1:s6:shadow5:outer6:taggedd6:shadow3:tagee"
+	var es embeddingStruct
+	// The promoted field c cannot be set through the nil pointer to an
+	// unexported struct. That is reported, and the rest is decoded.
+	err := Unmarshal(bytes.NewBufferString(input), &es)
+	if err == nil || err.Error() != "bencode: cannot set embedded pointer to unexported struct: bencode.embeddedPtr" {
+		t.Errorf("got error %v", err)
+	}
+	if es.A != 1 || es.embeddedPtr != nil || es.Shadow != "outer" || es.Embedded.Shadow != "" ||
+		es.secret != "" || es.hidden != 0 || es.Tagged.Shadow != "tag" {
+		t.Fatalf("got %+v", es)
+	}
+
+	var dk duplicateKeys
+	if err := Unmarshal(bytes.NewBufferString("d1:xi1e1:Ci3ee"), &dk); err != nil {
+		t.Fatal(err)
+	}
+	if dk != (duplicateKeys{0, 0, 3}) {
+		t.Fatalf("got %+v", dk)
+	}
+}
+
+func TestUnmarshalEmbeddedUnexportedPointer(t *testing.T) {
+	in := embeddingStruct{embeddedPtr: &embeddedPtr{7}}
+	var buf bytes.Buffer
+	if err := Marshal(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	var out embeddingStruct
+	if err := UnmarshalBytes(buf.Bytes(), &out); err == nil {
+		t.Errorf("UnmarshalBytes(%q) lost c without an error", buf.String())
+	}
+
+	// A non-nil pointer can be followed.
+	out = embeddingStruct{embeddedPtr: &embeddedPtr{}}
+	if err := UnmarshalBytes(buf.Bytes(), &out); err != nil || out.C != 7 {
+		t.Errorf("UnmarshalBytes into an allocated pointer: C = %d, %v", out.C, err)
+	}
+}
+
+type exportedEmbeddedPtr struct {
+	*EmbeddedInfo
+	Name string "name"
+}
+
+type EmbeddedInfo struct {
+	Length int "length"
+}
+
+func TestUnmarshalEmbeddedPointer(t *testing.T) {
+	var e exportedEmbeddedPtr
+	if err := Unmarshal(bytes.NewBufferString("d6:lengthi7e4:name1:xe"), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.EmbeddedInfo == nil || e.Length != 7 || e.Name != "x" {
+		t.Fatalf("got %+v", e)
+	}
+}
+
+type keyName string
+
+type reflectTargets struct {
+	Any    any
+	List   []int
+	Array  [2]int
+	Count  *int
+	Names  map[keyName]string
+	Nested map[string][]string
+}
+
+func TestUnmarshalReflectTargets(t *testing.T) {
+	const input = "d5:arrayli1ei2ei3ee3:anyli1e1:xe5:counti4e4:listli7ee5:namesd1:a1:be6:nestedd1:kl1:vee7:unknownd1:xli1eeee"
+	rt := reflectTargets{List: []int{1, 2, 3}, Array: [2]int{8, 9}}
+	if err := Unmarshal(bytes.NewBufferString(input), &rt); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rt.Any, []interface{}{int64(1), "x"}) {
+		t.Errorf("Any = %#v", rt.Any)
+	}
+	if !reflect.DeepEqual(rt.List, []int{7}) || rt.Array != [2]int{1, 2} {
+		t.Errorf("List = %v, Array = %v", rt.List, rt.Array)
+	}
+	if rt.Count == nil || *rt.Count != 4 {
+		t.Errorf("Count = %v", rt.Count)
+	}
+	if rt.Names["a"] != "b" || !reflect.DeepEqual(rt.Nested, map[string][]string{"k": {"v"}}) {
+		t.Errorf("Names = %v, Nested = %v", rt.Names, rt.Nested)
+	}
+}
+
+func TestUnmarshalFoldedKeys(t *testing.T) {
+	var s struct {
+		Name  string `bencode:"display name"`
+		Other string
+	}
+	if err := Unmarshal(bytes.NewBufferString("d12:DISPLAY NAME1:a5:oTHER1:be"), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "a" || s.Other != "b" {
+		t.Fatalf("got %+v", s)
+	}
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	for _, input := range []string{"d4:name", "li1e", "5:abc", "i12", "d1:xd1:yl"} {
+		var s struct{ Name string }
+		if err := Unmarshal(bytes.NewBufferString(input), &s); err != io.ErrUnexpectedEOF {
+			t.Errorf("Unmarshal(%q) = %v, want io.ErrUnexpectedEOF", input, err)
+		}
+	}
+	var s struct{}
+	if err := Unmarshal(bytes.NewBufferString(""), &s); err != io.EOF {
+		t.Errorf("Unmarshal of empty input = %v, want io.EOF", err)
+	}
+}
+
+type typeErrorFile struct {
+	Length int64    "length"
+	Path   []string "path"
+}
+
+type typeErrorTorrent struct {
+	Announce string "announce"
+	Info     struct {
+		Files  []typeErrorFile "files"
+		Pieces [4]byte         "pieces"
+	} "info"
+	Meta map[string]int "meta"
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	files := "5:filesld6:lengthi1eed6:lengthi2eee"
+	tests := []struct {
+		in    string
+		value string
+		typ   string
+		off   int64
+		field string
+	}{
+		{"d8:announcei7ee", "integer", "string", 11, "announce"},
+		{"d4:infod" + files[:len(files)-1] + "d6:length3:abceeee", "string", "int64", 51, "info.files[2].length"},
+		{"d4:infod" + files[:len(files)-1] + "d4:pathli1eeeeee", "integer", "string", 50, "info.files[2].path[0]"},
+		{"d4:infod6:pieces3:abcee", "3-byte string", "[4]uint8", 16, "info.pieces"},
+		{"d4:metad1:xlee4:infod5:filesd4:x   i1eeee", "list", "int", 11, "meta.x"},
+		{"d4:infoi1ee", "integer", "struct { Files []bencode.typeErrorFile \"files\"; Pieces [4]uint8 \"pieces\" }", 7, "info"},
+		{"li1ee", "list", "bencode.typeErrorTorrent", 0, ""},
+	}
+	for _, tt := range tests {
+		var tor typeErrorTorrent
+		err := Unmarshal(bytes.NewBufferString(tt.in), &tor)
+		var ute *UnmarshalTypeError
+		if !errors.As(err, &ute) {
+			t.Errorf("Unmarshal(%q) = %v, want an UnmarshalTypeError", tt.in, err)
+			continue
+		}
+		if ute.Value != tt.value || ute.Type.String() != tt.typ || ute.Offset != tt.off || ute.Field != tt.field {
+			t.Errorf("Unmarshal(%q) = %+v, want %s into %s at %d, field %q",
+				tt.in, *ute, tt.value, tt.typ, tt.off, tt.field)
+		}
+		bytesErr := UnmarshalBytes([]byte(tt.in), &tor)
+		if bytesErr == nil || bytesErr.Error() != err.Error() {
+			t.Errorf("UnmarshalBytes(%q) = %v, want %v", tt.in, bytesErr, err)
+		}
+	}
+
+	// Values that fit are still stored.
+	var tor typeErrorTorrent
+	err := Unmarshal(bytes.NewBufferString("d8:announcei1e4:infod"+files+"e4:metad1:ai1e1:b0:1:ci3eee"), &tor)
+	want := `bencode: cannot unmarshal integer at announce into Go value of type string`
+	if err == nil || err.Error() != want {
+		t.Errorf("Unmarshal = %v, want %s", err, want)
+	}
+	if len(tor.Info.Files) != 2 || tor.Info.Files[1].Length != 2 || tor.Meta["a"] != 1 || tor.Meta["c"] != 3 {
+		t.Errorf("Unmarshal stored %+v", tor)
+	}
+}
+
+type sizedFields struct {
+	Int8    int8    "int8"
+	Int32   int32   "int32"
+	Int     int     "int"
+	Uint8   uint8   "uint8"
+	Uint32  uint32  "uint32"
+	Uint64  uint64  "uint64"
+	Float32 float32 "float32"
+	Pieces  []int16 "pieces"
+}
+
+func TestUnmarshalOverflow(t *testing.T) {
+	tests := []struct {
+		in    string
+		value string
+		field string
+	}{
+		{"d4:int8i128ee", "integer 128", "int8"},
+		{"d4:int8i-129ee", "integer -129", "int8"},
+		{"d5:int32i2147483648ee", "integer 2147483648", "int32"},
+		{"d3:inti9223372036854775808ee", "integer 9223372036854775808", "int"},
+		{"d3:inti-7.574E+20ee", "integer -7.574E+20", "int"},
+		{"d3:inti7.574E+20ee", "integer 7.574E+20", "int"},
+		{"d5:uint8i256ee", "integer 256", "uint8"},
+		{"d6:uint32i-1ee", "integer -1", "uint32"},
+		{"d6:uint64i-1ee", "integer -1", "uint64"},
+		{"d6:uint64i18446744073709551616ee", "integer 18446744073709551616", "uint64"},
+		{"d6:uint64i-0.5E1ee", "integer -0.5E1", "uint64"},
+		{"d7:float32i1E39ee", "integer 1E39", "float32"},
+		{"d6:piecesli1ei32768eee", "integer 32768", "pieces[1]"},
+	}
+	for _, tt := range tests {
+		var v sizedFields
+		err := Unmarshal(bytes.NewBufferString(tt.in), &v)
+		var ute *UnmarshalTypeError
+		if !errors.As(err, &ute) || ute.Value != tt.value || ute.Field != tt.field {
+			t.Errorf("Unmarshal(%q) = %v, want %s at %s", tt.in, err, tt.value, tt.field)
+			continue
+		}
+		if !reflect.DeepEqual(v, sizedFields{Pieces: v.Pieces}) {
+			t.Errorf("Unmarshal(%q) stored %+v", tt.in, v)
+		}
+	}
+
+	// The limits of each type fit.
+	in := "d7:float32i3.4E38e3:inti-9223372036854775808e5:int32i-2147483648e4:int8i127e6:uint32i4294967295e6:uint64i18446744073709551615e5:uint8i255ee"
+	var v sizedFields
+	if err := Unmarshal(bytes.NewBufferString(in), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := sizedFields{math.MaxInt8, math.MinInt32, math.MinInt64, math.MaxUint8, math.MaxUint32, math.MaxUint64, 3.4e38, nil}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", v, want)
+	}
+}
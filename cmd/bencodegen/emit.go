@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// An emitter writes the body of a generated method.
+type emitter struct {
+	g    *generator
+	buf  bytes.Buffer
+	vars int // count of local variables declared
+
+	// Set when the body refers to the start of the output or to err,
+	// which then have to be declared.
+	usesStart bool
+	usesErr   bool
+}
+
+func (e *emitter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&e.buf, format, args...)
+	e.buf.WriteByte('\n')
+}
+
+// newVar returns a fresh local variable name starting with prefix.
+func (e *emitter) newVar(prefix string) string {
+	e.vars++
+	return prefix + strconv.Itoa(e.vars)
+}
+
+// appendMethod writes the AppendBencode and MarshalBencode methods of s.
+func (g *generator) appendMethod(buf *bytes.Buffer, s *structType) {
+	e := &emitter{g: g}
+	e.printf("b = append(b, 'd')")
+	for _, f := range s.sortedByKey() {
+		expr := "x." + f.name
+		cond := nilCheck(expr, f.typ)
+		if f.omitEmpty {
+			if empty := emptyCheck(expr, f.typ); empty != "" && empty != cond {
+				if cond != "" {
+					cond += " && "
+				}
+				cond += empty
+			}
+		}
+		if cond != "" {
+			e.printf("if %s {", cond)
+		}
+		e.printf("b = append(b, %s...)", strconv.Quote(strconv.Itoa(len(f.key))+":"+f.key))
+		e.encode(expr, f.typ, cond != "")
+		if cond != "" {
+			e.printf("}")
+		}
+	}
+	e.printf("b = append(b, 'e')")
+	e.printf("return b, nil")
+
+	fmt.Fprintf(buf, "\n// AppendBencode appends the bencode encoding of x to b, as Marshal would,\n")
+	fmt.Fprintf(buf, "// and returns the extended buffer. If an error occurs, it returns b and\n// the error.\n")
+	fmt.Fprintf(buf, "func (x *%s) AppendBencode(b []byte) ([]byte, error) {\n", s.name)
+	if e.usesStart {
+		buf.WriteString("start := len(b)\n")
+	}
+	if e.usesErr {
+		buf.WriteString("var err error\n")
+	}
+	buf.Write(e.buf.Bytes())
+	buf.WriteString("}\n")
+
+	fmt.Fprintf(buf, "\n// MarshalBencode returns the bencode encoding of x.\n")
+	fmt.Fprintf(buf, "func (x %s) MarshalBencode() ([]byte, error) {\n", s.name)
+	buf.WriteString("return x.AppendBencode(nil)\n}\n")
+}
+
+// encode writes code appending the encoding of expr, of type t, to b. If
+// nonNil is set, expr is known not to be a nil pointer.
+func (e *emitter) encode(expr string, t types.Type, nonNil bool) {
+	switch e.g.kind(t) {
+	case kindBool:
+		e.printf("if %s {", expr)
+		e.printf(`b = append(b, "i1e"...)`)
+		e.printf("} else {")
+		e.printf(`b = append(b, "i0e"...)`)
+		e.printf("}")
+	case kindInt:
+		e.printf("b = bencodegenAppendInt(b, %s)", convert(expr, t, types.Int64))
+	case kindUint:
+		e.printf("b = bencodegenAppendUint(b, %s)", convert(expr, t, types.Uint64))
+	case kindString:
+		e.printf("b = bencodegenAppendString(b, %s)", convert(expr, t, types.String))
+	case kindBytes, kindByteArray:
+		if e.g.kind(t) == kindByteArray {
+			expr += "[:]"
+		}
+		if types.Identical(elem(t), types.Typ[types.Uint8]) {
+			e.printf("b = bencodegenAppendBytes(b, %s)", expr)
+		} else {
+			e.printf("b = bencodegenAppendByteElems(b, %s)", expr)
+		}
+	case kindSlice, kindArray:
+		i := e.newVar("i")
+		e.printf("b = append(b, 'l')")
+		e.printf("for %s := range %s {", i, expr)
+		e.encode(expr+"["+i+"]", elem(t), false)
+		e.printf("}")
+		e.printf("b = append(b, 'e')")
+	case kindMap:
+		k, v := e.newVar("k"), e.newVar("v")
+		e.printf("b = append(b, 'd')")
+		e.printf("for _, %s := range bencodegenSortedKeys(%s) {", k, expr)
+		e.printf("%s := %s[%s]", v, expr, k)
+		cond := nilCheck(v, elem(t))
+		if cond != "" {
+			e.printf("if !(%s) {", cond)
+			e.printf("continue")
+			e.printf("}")
+		}
+		e.printf("b = bencodegenAppendString(b, string(%s))", k)
+		e.encode(v, elem(t), cond != "")
+		e.printf("}")
+		e.printf("b = append(b, 'e')")
+	case kindPtr:
+		if !nonNil {
+			e.usesStart = true
+			e.printf("if %s == nil {", expr)
+			e.printf("return b[:start], bencodegenErrNilPointer")
+			e.printf("}")
+		}
+		if e.g.kind(elem(t)) == kindStruct {
+			// Call the pointer method directly.
+			e.appendStruct(expr)
+			break
+		}
+		e.encode("(*"+expr+")", elem(t), false)
+	case kindStruct:
+		e.appendStruct(expr)
+	default:
+		e.usesStart, e.usesErr = true, true
+		e.printf("if b, err = bencode.AppendMarshal(b, %s); err != nil {", expr)
+		e.printf("return b[:start], err")
+		e.printf("}")
+	}
+}
+
+// appendStruct writes code appending the encoding of a listed struct type
+// or a pointer to one.
+func (e *emitter) appendStruct(expr string) {
+	e.usesStart, e.usesErr = true, true
+	e.printf("if b, err = %s.AppendBencode(b); err != nil {", expr)
+	e.printf("return b[:start], err")
+	e.printf("}")
+}
+
+// convert returns expr, of type t, converted to the basic type of the
+// given kind.
+func convert(expr string, t types.Type, kind types.BasicKind) string {
+	if types.Identical(t, types.Typ[kind]) {
+		return expr
+	}
+	if strings.HasPrefix(expr, "(*") && strings.HasSuffix(expr, ")") {
+		expr = expr[1 : len(expr)-1]
+	}
+	return types.Typ[kind].Name() + "(" + expr + ")"
+}
+
+// nilCheck returns a condition that holds unless expr, of type t, is a
+// value that Marshal leaves out of dictionaries: a nil pointer, interface
+// or RawMessage, or a zero Value. It returns "" if there is none.
+func nilCheck(expr string, t types.Type) string {
+	switch {
+	case isNamed(t, bencodePath, "RawMessage"):
+		return expr + " != nil"
+	case isNamed(t, bencodePath, "Value"):
+		return expr + ".Kind() != bencode.KindInvalid"
+	}
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Interface:
+		return expr + " != nil"
+	}
+	return ""
+}
+
+// emptyCheck returns a condition that holds unless expr, of type t, is a
+// value that the omitempty option leaves out. It returns "" if there is
+// none.
+func emptyCheck(expr string, t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsBoolean != 0:
+			return expr
+		case info&(types.IsInteger|types.IsFloat) != 0:
+			return expr + " != 0"
+		case info&types.IsString != 0:
+			return "len(" + expr + ") != 0"
+		}
+	case *types.Array:
+		if u.Len() == 0 {
+			return "false"
+		}
+	case *types.Slice, *types.Map:
+		return "len(" + expr + ") != 0"
+	case *types.Pointer, *types.Interface:
+		return expr + " != nil"
+	}
+	return ""
+}
+
+// decodeMethod writes the UnmarshalBencode and decodeBencode methods of s.
+func (g *generator) decodeMethod(buf *bytes.Buffer, s *structType) {
+	fmt.Fprintf(buf, "\n// UnmarshalBencode decodes data into x, as Unmarshal would.\n")
+	fmt.Fprintf(buf, "func (x *%s) UnmarshalBencode(data []byte) error {\n", s.name)
+	buf.WriteString("return bencodegenUnmarshal(data, x.decodeBencode)\n}\n")
+
+	e := &emitter{g: g}
+	e.printf("if err := bencodegenBegin(d, bencode.DictStart, x); err != nil {")
+	e.printf("return err")
+	e.printf("}")
+	e.printf("var first error")
+	e.printf("for d.More() {")
+	e.printf("key, err := d.Token()")
+	e.printf("if err != nil {")
+	e.printf("return err")
+	e.printf("}")
+	if len(s.fields) > 0 {
+		// Find the field as Unmarshal does: by its key, or else by its
+		// key or Go name ignoring case, the first field winning.
+		e.printf("f := -1")
+		e.printf("switch string(key.Value) {")
+		for i, f := range s.fields {
+			e.printf("case %s:", strconv.Quote(f.key))
+			e.printf("f = %d", i)
+		}
+		e.printf("}")
+		e.printf("if f < 0 {")
+		e.printf("switch bencodegenFold(key.Value) {")
+		seen := map[string]bool{}
+		for i, f := range s.fields {
+			var names []string
+			for _, name := range []string{f.key, f.name} {
+				if folded := strings.ToLower(name); !seen[folded] {
+					seen[folded] = true
+					names = append(names, strconv.Quote(folded))
+				}
+			}
+			if len(names) > 0 {
+				e.printf("case %s:", strings.Join(names, ", "))
+				e.printf("f = %d", i)
+			}
+		}
+		e.printf("}")
+		e.printf("}")
+		e.printf("switch f {")
+		for i, f := range s.fields {
+			e.printf("case %d:", i)
+			e.decode("x."+f.name, f.typ, strconv.Quote(f.key))
+		}
+		e.printf("default:")
+	}
+	e.printf("if err = d.Skip(); err != nil {")
+	e.printf("return err")
+	e.printf("}")
+	if len(s.fields) > 0 {
+		e.printf("}")
+	}
+	e.printf("}")
+	e.printf("if err := bencodegenEnd(d); err != nil {")
+	e.printf("return err")
+	e.printf("}")
+	e.printf("return first")
+
+	fmt.Fprintf(buf, "\nfunc (x *%s) decodeBencode(d *bencode.Decoder) error {\n", s.name)
+	buf.Write(e.buf.Bytes())
+	buf.WriteString("}\n")
+}
+
+// decode writes code decoding the next value into expr, of type t. Type
+// errors are recorded in first, with the path given by the Go expression
+// path, and other errors are returned.
+func (e *emitter) decode(expr string, t types.Type, path string) {
+	switch e.g.kind(t) {
+	case kindBool:
+		e.printf("err = bencodegenBool(d, &%s)", expr)
+	case kindInt:
+		e.printf("err = bencodegenInt(d, &%s)", expr)
+	case kindUint:
+		e.printf("err = bencodegenUint(d, &%s)", expr)
+	case kindString:
+		e.printf("err = bencodegenString(d, &%s)", expr)
+	case kindBytes:
+		if types.Identical(elem(t), types.Typ[types.Uint8]) {
+			e.printf("err = bencodegenBytes(d, &%s)", expr)
+		} else {
+			e.printf("err = bencodegenByteElems(d, &%s)", expr)
+		}
+	case kindByteArray:
+		e.printf("err = bencodegenByteArray(d, %s[:], &%s)", expr, expr)
+	case kindSlice, kindArray:
+		i := e.newVar("i")
+		e.printf("if err = bencodegenBegin(d, bencode.ListStart, &%s); err == nil {", expr)
+		e.printf("%s := 0", i)
+		e.printf("for ; d.More(); %s++ {", i)
+		if e.g.kind(t) == kindSlice {
+			e.printf("%s = bencodegenGrow(%s, %s)", expr, expr, i)
+		} else {
+			e.printf("if %s >= len(%s) {", i, expr)
+			e.printf("if err = d.Skip(); err != nil {")
+			e.printf("return err")
+			e.printf("}")
+			e.printf("continue")
+			e.printf("}")
+		}
+		e.decode(expr+"["+i+"]", elem(t), path+` + "[" + strconv.Itoa(`+i+`) + "]"`)
+		e.printf("}")
+		if e.g.kind(t) == kindSlice {
+			e.printf("%s = bencodegenTrim(%s, %s)", expr, expr, i)
+		} else {
+			e.printf("if %s < len(%s) {", i, expr)
+			e.printf("clear(%s[%s:])", expr, i)
+			e.printf("}")
+		}
+		e.printf("err = bencodegenEnd(d)")
+		e.printf("}")
+	case kindMap:
+		k, v := e.newVar("k"), e.newVar("v")
+		e.printf("if err = bencodegenBegin(d, bencode.DictStart, &%s); err == nil {", expr)
+		e.printf("bencodegenMakeMap(&%s)", expr)
+		e.printf("for d.More() {")
+		e.printf("var %s string", k)
+		e.printf("if %s, err = bencodegenKey(d); err != nil {", k)
+		e.printf("return err")
+		e.printf("}")
+		e.printf("%s := bencodegenElem(%s)", v, expr)
+		e.decode(v, elem(t), path+` + "." + `+k)
+		e.printf("bencodegenMapSet(%s, %s, %s)", expr, k, v)
+		e.printf("}")
+		e.printf("err = bencodegenEnd(d)")
+		e.printf("}")
+	case kindPtr:
+		p := e.newVar("p")
+		e.printf("%s := bencodegenAlloc(&%s)", p, expr)
+		if e.g.kind(elem(t)) == kindStruct {
+			e.printf("err = %s.decodeBencode(d)", p)
+			break
+		}
+		e.decode("(*"+p+")", elem(t), path)
+		return
+	case kindStruct:
+		e.printf("err = %s.decodeBencode(d)", expr)
+	default:
+		e.printf("err = d.DecodeInto(&%s)", expr)
+	}
+	e.printf("if err != nil {")
+	e.printf("if err = bencodegenKeep(&first, err, %s); err != nil {", path)
+	e.printf("return err")
+	e.printf("}")
+	e.printf("}")
+}
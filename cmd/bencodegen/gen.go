@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jackpal/bencode-go/internal/tags"
+)
+
+const bencodePath = "github.com/jackpal/bencode-go"
+
+// generatedPrefix starts the first line of every file bencodegen writes.
+const generatedPrefix = "// Code generated by \"bencodegen"
+
+// supportFile and testSupportFile hold the helper functions shared by the
+// output of every run of bencodegen in a package.
+const (
+	supportFile     = "bencodegen_support.go"
+	testSupportFile = "bencodegen_support_test.go"
+)
+
+// A generator holds a type-checked package and the struct types to
+// generate methods for.
+type generator struct {
+	command string // the command line recorded in the output
+	pkg     *types.Package
+	structs []*structType
+	listed  map[*types.TypeName]bool
+}
+
+// A structType is a struct type named on the command line.
+type structType struct {
+	name   string
+	fields []field // in the order of the struct definition
+}
+
+// A field is a struct field that is encoded as a dictionary entry.
+type field struct {
+	name      string // the Go field name
+	key       string // the dictionary key
+	tagged    bool
+	omitEmpty bool
+	typ       types.Type
+}
+
+// load type-checks the package in dir, leaving out the previous output of
+// bencodegen, and finds the named struct types.
+func load(dir, output string, names []string) (*generator, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Comments) > 0 && strings.HasPrefix(f.Comments[0].Text(), generatedPrefix[3:]) {
+			continue
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check(bp.Name, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, listed: map[*types.TypeName]bool{}}
+	for _, name := range names {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("no type %s in package %s", name, pkg.Name())
+		}
+		g.listed[obj] = true
+	}
+	for _, name := range names {
+		s, err := g.structType(pkg.Scope().Lookup(name).(*types.TypeName))
+		if err != nil {
+			return nil, err
+		}
+		g.structs = append(g.structs, s)
+	}
+	return g, nil
+}
+
+// structType works out the dictionary entries of the named struct type,
+// following the rules of Marshal.
+func (g *generator) structType(obj *types.TypeName) (*structType, error) {
+	named, ok := obj.Type().(*types.Named)
+	if !ok || obj.IsAlias() || named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s is not a defined, non-generic type", obj.Name())
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct type", obj.Name())
+	}
+	methods := types.NewMethodSet(types.NewPointer(named))
+	for _, m := range []string{"AppendBencode", "MarshalBencode", "UnmarshalBencode", "decodeBencode"} {
+		if methods.Lookup(g.pkg, m) != nil {
+			return nil, fmt.Errorf("%s already has a method %s", obj.Name(), m)
+		}
+	}
+
+	s := &structType{name: obj.Name()}
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		if v.Embedded() {
+			ft := v.Type()
+			if p, ok := ft.(*types.Pointer); ok {
+				ft = p.Elem()
+			}
+			_, isStruct := ft.Underlying().(*types.Struct)
+			if !v.Exported() && !isStruct {
+				continue
+			}
+			if key, _ := tags.Key(v.Name(), tag); key == "-" {
+				continue
+			}
+			if isStruct && !tags.IsTagged(tag) {
+				return nil, fmt.Errorf("%s.%s: promoted fields of embedded structs are not supported", obj.Name(), v.Name())
+			}
+			if !v.Exported() {
+				return nil, fmt.Errorf("%s.%s: unexported embedded fields are not supported", obj.Name(), v.Name())
+			}
+			if n, ok := ft.(*types.Named); ok && (g.listed[n.Obj()] || hasBencodeMethods(ft)) {
+				// The methods would be promoted, and Marshal would
+				// use them to encode the whole of the struct.
+				return nil, fmt.Errorf("%s.%s: embedded fields with bencode methods are not supported", obj.Name(), v.Name())
+			}
+		} else if !v.Exported() {
+			continue
+		}
+		key, omitEmpty := tags.Key(v.Name(), tag)
+		if key == "-" {
+			continue
+		}
+		s.fields = append(s.fields, field{
+			name:      v.Name(),
+			key:       key,
+			tagged:    tags.IsTagged(tag),
+			omitEmpty: omitEmpty,
+			typ:       v.Type(),
+		})
+	}
+
+	// Of several fields sharing a key, a single tagged one wins, and
+	// otherwise all of them are left out.
+	count := map[string]int{}
+	taggedCount := map[string]int{}
+	for _, f := range s.fields {
+		count[f.key]++
+		if f.tagged {
+			taggedCount[f.key]++
+		}
+	}
+	fields := s.fields[:0]
+	for _, f := range s.fields {
+		if count[f.key] == 1 || taggedCount[f.key] == 1 && f.tagged {
+			fields = append(fields, f)
+		}
+	}
+	s.fields = fields
+	return s, nil
+}
+
+// sortedByKey returns the fields of s in the order Marshal writes them.
+func (s *structType) sortedByKey() []field {
+	fields := append([]field(nil), s.fields...)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+	return fields
+}
+
+// A kind is the way generated code handles a type.
+type kind int
+
+const (
+	kindOther     kind = iota // passed to AppendMarshal and DecodeInto
+	kindBool                  // a boolean, as an integer
+	kindInt                   // a signed integer
+	kindUint                  // an unsigned integer
+	kindString                // a string
+	kindBytes                 // a slice of bytes, as a string
+	kindByteArray             // an array of bytes, as a string
+	kindSlice                 // a slice, as a list
+	kindArray                 // an array, as a list
+	kindMap                   // a map with string keys, as a dictionary
+	kindPtr                   // a pointer to a type handled by generated code
+	kindStruct                // one of the listed struct types
+)
+
+// kind classifies t.
+func (g *generator) kind(t types.Type) kind {
+	if hasBencodeMethods(t) || isNamed(t, bencodePath, "Number") || isNamed(t, "math/big", "Int") {
+		return kindOther
+	}
+	if n, ok := t.(*types.Named); ok && g.listed[n.Obj()] {
+		return kindStruct
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsBoolean != 0:
+			return kindBool
+		case info&types.IsInteger != 0 && info&types.IsUnsigned != 0:
+			return kindUint
+		case info&types.IsInteger != 0:
+			return kindInt
+		case info&types.IsString != 0:
+			return kindString
+		}
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			return kindBytes
+		}
+		return kindSlice
+	case *types.Array:
+		if isByte(u.Elem()) {
+			return kindByteArray
+		}
+		return kindArray
+	case *types.Map:
+		if k, ok := u.Key().Underlying().(*types.Basic); ok && k.Info()&types.IsString != 0 {
+			return kindMap
+		}
+	case *types.Pointer:
+		if g.kind(u.Elem()) != kindOther {
+			return kindPtr
+		}
+	}
+	return kindOther
+}
+
+// elem returns the element type of a slice, array, map or pointer type.
+func elem(t types.Type) types.Type {
+	return t.Underlying().(interface{ Elem() types.Type }).Elem()
+}
+
+// isByte reports whether t is byte or a type defined from it, whose
+// slices and arrays are encoded as strings.
+func isByte(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
+
+func isNamed(t types.Type, path, name string) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == path && n.Obj().Name() == name
+}
+
+// hasBencodeMethods reports whether t, or a pointer to it, has its own
+// MarshalBencode or UnmarshalBencode method, which Marshal and Unmarshal
+// would use.
+func hasBencodeMethods(t types.Type) bool {
+	if _, ok := t.Underlying().(*types.Pointer); !ok {
+		if _, ok := t.Underlying().(*types.Interface); ok {
+			return false
+		}
+		t = types.NewPointer(t)
+	}
+	ms := types.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		if name := ms.At(i).Obj().Name(); name == "MarshalBencode" || name == "UnmarshalBencode" {
+			return true
+		}
+	}
+	return false
+}
+
+// generate returns the source of the file holding the methods.
+func (g *generator) generate() ([]byte, error) {
+	var body bytes.Buffer
+	for _, s := range g.structs {
+		g.appendMethod(&body, s)
+		g.decodeMethod(&body, s)
+	}
+	return g.file(g.command, body.Bytes(), "bytes", "errors", "reflect", "slices", "strconv", "strings")
+}
+
+// generateTests returns the source of the file holding the tests of the
+// methods.
+func (g *generator) generateTests() ([]byte, error) {
+	var body bytes.Buffer
+	for _, s := range g.structs {
+		fmt.Fprintf(&body, testTemplate, s.name)
+	}
+	return g.file(g.command, body.Bytes(), "bytes", "reflect", "strconv", "strings", "testing")
+}
+
+// generateSupport returns the source of supportFile. It does not depend
+// on the types, so every run in a package writes the same file.
+func (g *generator) generateSupport() ([]byte, error) {
+	return g.file("bencodegen", []byte(supportSource), "bytes", "errors", "reflect", "slices", "strconv", "strings")
+}
+
+// generateTestSupport returns the source of testSupportFile.
+func (g *generator) generateTestSupport() ([]byte, error) {
+	return g.file("bencodegen", []byte(testSupportSource), "bytes", "reflect", "strconv", "strings", "testing")
+}
+
+// file returns the formatted source of a file holding body, written by
+// command. It imports the bencode package, and those of the standard
+// packages std that body uses.
+func (g *generator) file(command string, body []byte, std ...string) ([]byte, error) {
+	used, err := usedPackages(body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s%s\"; DO NOT EDIT.\n\n", generatedPrefix, strings.TrimPrefix(command, "bencodegen"))
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n", g.pkg.Name())
+	for _, path := range std {
+		if used[path] {
+			fmt.Fprintf(&buf, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&buf, "\n\t%q\n)\n", bencodePath)
+	buf.Write(body)
+	return formatSource(buf.Bytes())
+}
+
+// usedPackages returns the names that body, a list of declarations,
+// qualifies identifiers with, which include the packages it uses.
+func usedPackages(body []byte) (map[string]bool, error) {
+	src := append([]byte("package p\n"), body...)
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: invalid Go generated: %v\n%s", err, src)
+	}
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	return used, nil
+}
+
+func formatSource(src []byte) ([]byte, error) {
+	out, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: invalid Go generated: %v\n%s", err, src)
+	}
+	return out, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exampleRuns are the go:generate directives of package example.
+var exampleRuns = []struct {
+	types, output string
+}{
+	{"Torrent,Info,File,Message,Args,Reply", ""},
+	{"Kitchen", "kitchen_gen.go"},
+}
+
+func TestExampleUpToDate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	for _, run := range exampleRuns {
+		output, command := "bencode_gen.go", "bencodegen -type "+run.types
+		if run.output != "" {
+			output = run.output
+			command += " -output " + run.output
+		}
+		g, err := load(dir, output, strings.Split(run.types, ","))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.command = command
+		for name, gen := range map[string]func() ([]byte, error){
+			output: g.generate,
+			strings.TrimSuffix(output, ".go") + "_test.go": g.generateTests,
+			supportFile:     g.generateSupport,
+			testSupportFile: g.generateTestSupport,
+		} {
+			got, err := gen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s is out of date; run go generate in %s", name, dir)
+			}
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		src, types, err string
+	}{
+		{"type T int", "T", "T is not a struct type"},
+		{"type T struct{}", "U", "no type U in package p"},
+		{"type T = struct{}", "T", "T is not a defined, non-generic type"},
+		{"type T[E any] struct{ E E }", "T", "T is not a defined, non-generic type"},
+		{"type E struct{ A int }; type T struct{ E }", "T", "T.E: promoted fields of embedded structs are not supported"},
+		{"type E struct{ A int }; type T struct{ *E }", "T", "T.E: promoted fields of embedded structs are not supported"},
+		{"type E struct{ A int }; type T struct{ E `bencode:\"e\"` }", "T,E", "T.E: embedded fields with bencode methods are not supported"},
+		{"type T struct{}; func (T) MarshalBencode() ([]byte, error) { return nil, nil }", "T", "T already has a method MarshalBencode"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte("package p\n"+tt.src+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		_, err := load(dir, "bencode_gen.go", strings.Split(tt.types, ","))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("load(%q, %s) = %v, want %q", tt.src, tt.types, err, tt.err)
+		}
+	}
+}
+
+func TestLoadSkipsOutput(t *testing.T) {
+	// The methods from an earlier run are not taken as the type's own.
+	dir := t.TempDir()
+	files := map[string]string{
+		"p.go":                "package p\ntype T struct{ A int }\n",
+		"bencode_gen.go":      "package p\nfunc (T) MarshalBencode() ([]byte, error) { return nil, nil }\n",
+		"other_bencode.go":    generatedPrefix + " -type U\"; DO NOT EDIT.\n\npackage p\nfunc (*T) UnmarshalBencode([]byte) error { return nil }\n",
+		"bencode_gen_test.go": "package p\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g, err := load(dir, "bencode_gen.go", []string{"T"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.generate(); err != nil {
+		t.Fatal(err)
+	}
+}
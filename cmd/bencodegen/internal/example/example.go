@@ -0,0 +1,140 @@
+// Package example holds types for testing the methods that bencodegen
+// generates. Its generated files are checked by the tests of bencodegen to
+// be up to date. They come from two runs, so that the package also checks
+// that the output of several runs compiles together.
+package example
+
+//go:generate go run github.com/jackpal/bencode-go/cmd/bencodegen -type Torrent,Info,File,Message,Args,Reply
+//go:generate go run github.com/jackpal/bencode-go/cmd/bencodegen -type Kitchen -output kitchen_gen.go
+
+import (
+	"math/big"
+
+	"github.com/jackpal/bencode-go"
+)
+
+// A Torrent is a metainfo file.
+type Torrent struct {
+	Announce     string     `bencode:"announce"`
+	AnnounceList [][]string `bencode:"announce-list,omitempty"`
+	Comment      string     `bencode:"comment,omitempty"`
+	CreatedBy    string     `bencode:"created by,omitempty"`
+	CreationDate int64      `bencode:"creation date,omitempty"`
+	Info         Info       `bencode:"info"`
+	URLList      []string   `bencode:"url-list,omitempty"`
+}
+
+// Info is the info dictionary of a torrent.
+type Info struct {
+	Name        string `bencode:"name"`
+	PieceLength int64  `bencode:"piece length"`
+	Pieces      []byte `bencode:"pieces"`
+	Length      int64  `bencode:"length,omitempty"`
+	Files       []File `bencode:"files,omitempty"`
+	Private     bool   `bencode:"private,omitempty"`
+}
+
+// A File is one of the files of a multi-file torrent.
+type File struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+	MD5Sum string   `bencode:"md5sum,omitempty"`
+}
+
+// A Message is a query, response or error of the DHT protocol.
+type Message struct {
+	T string        `bencode:"t"`
+	Y string        `bencode:"y"`
+	Q string        `bencode:"q,omitempty"`
+	A *Args         `bencode:"a"`
+	R *Reply        `bencode:"r"`
+	E []interface{} `bencode:"e,omitempty"`
+	V string        `bencode:"v,omitempty"`
+}
+
+// Args are the arguments of a DHT query.
+type Args struct {
+	ID          [20]byte `bencode:"id"`
+	Target      []byte   `bencode:"target,omitempty"`
+	InfoHash    []byte   `bencode:"info_hash,omitempty"`
+	Port        uint16   `bencode:"port,omitempty"`
+	ImpliedPort bool     `bencode:"implied_port,omitempty"`
+	Token       string   `bencode:"token,omitempty"`
+}
+
+// A Reply is the response to a DHT query.
+type Reply struct {
+	ID     [20]byte `bencode:"id"`
+	Nodes  []byte   `bencode:"nodes,omitempty"`
+	Values [][]byte `bencode:"values,omitempty"`
+	Token  string   `bencode:"token,omitempty"`
+}
+
+// Port is a named integer type.
+type Port uint16
+
+// Port2 is embedded in Kitchen without a tag.
+type Port2 int
+
+// Extra is a struct type embedded in Kitchen with a tag, which is not
+// passed to bencodegen.
+type Extra struct {
+	Note string `bencode:"note"`
+}
+
+// Tags is a named slice type.
+type Tags []string
+
+// Octet is a type defined from byte, whose slices and arrays are encoded
+// as strings.
+type Octet byte
+
+// Kitchen has a field of every kind that bencodegen handles, and the
+// different forms of struct tags.
+type Kitchen struct {
+	Bool    bool
+	Int     int
+	Int8    int8 `bencode:"i8"`
+	Int16   int16
+	Int32   int32
+	Uint    uint
+	Uint8   uint8
+	Uint32  uint32
+	Uint64  uint64
+	Port    Port    `bencode:"port,omitempty"`
+	Legacy  string  "old style"
+	Upper   string  `bencode:"UPPER"`
+	Ignored string  `bencode:"-"`
+	Dash    string  `bencode:"-,"`
+	Dup1    string  `bencode:"dup"`
+	Dup2    string  `bencode:"dup"`
+	Win     string  `bencode:"win"`
+	Win2    string  `json:"win"`
+	Options string  `bencode:",omitempty"`
+	Float   float64 `bencode:",omitempty"`
+	hidden  int
+
+	Bytes  []byte
+	Hash   [4]byte
+	Octets []Octet
+	Key    [4]Octet
+	Empty  [0]int `bencode:",omitempty"`
+	Tags   Tags
+	Matrix [][2]int
+	Map    map[string]int64
+	Files  map[string]*File
+	Nested map[string][]map[string]string
+	Ptr    *int
+	PtrPtr **string `bencode:",omitempty"`
+	List   []*File
+	Tree   *Kitchen `bencode:",omitempty"`
+	Extra  `bencode:"extra"`
+	Port2
+
+	Any     interface{}
+	Number  bencode.Number
+	Raw     bencode.RawMessage
+	Value   bencode.Value
+	Big     *big.Int
+	Torrent *Torrent `bencode:",omitempty"`
+}
@@ -0,0 +1,183 @@
+package example
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackpal/bencode-go"
+)
+
+func TestUnmarshalKeys(t *testing.T) {
+	// Keys are matched exactly, and then ignoring case against the key
+	// or the Go name, as by Unmarshal.
+	in := "d8:ANNOUNCE1:a12:announcelistll1:bee5:Extrai1e4:infod4:name1:ne7:COMMENT1:ce"
+	var got Torrent
+	if err := got.UnmarshalBencode([]byte(in)); err != nil {
+		t.Fatal(err)
+	}
+	want := Torrent{Announce: "a", AnnounceList: [][]string{{"b"}}, Comment: "c", Info: Info{Name: "n"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalBencode(%q) = %+v, want %+v", in, got, want)
+	}
+
+	var k Kitchen
+	in = "d5:upper1:a5:UPPER1:b3:dup1:c9:old style1:d4:Dup11:ee"
+	if err := k.UnmarshalBencode([]byte(in)); err != nil {
+		t.Fatal(err)
+	}
+	if k.Upper != "b" || k.Legacy != "d" || k.Dup1 != "" || k.Dup2 != "" {
+		t.Errorf("UnmarshalBencode(%q) = %+v", in, k)
+	}
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	in := "d4:infod5:filesld6:lengthi1ee" + "d6:length1:x4:pathl1:aee" + "e4:name1:nee"
+	var got Torrent
+	err := got.UnmarshalBencode([]byte(in))
+	var ute *bencode.UnmarshalTypeError
+	if !errors.As(err, &ute) {
+		t.Fatalf("UnmarshalBencode(%q) = %v, want an UnmarshalTypeError", in, err)
+	}
+	if ute.Field != "info.files[1].length" || ute.Value != "string" {
+		t.Errorf("error = %+v, want a string at info.files[1].length", *ute)
+	}
+	// Decoding goes on after the error.
+	if got.Info.Name != "n" || len(got.Info.Files) != 2 || got.Info.Files[1].Path[0] != "a" {
+		t.Errorf("decoded %+v", got)
+	}
+
+	tests := []struct {
+		in    string
+		field string
+		value string
+	}{
+		{"d4:porti70000ee", "port", "integer 70000"},
+		{"d4:Uinti-1ee", "Uint", "integer -1"},
+		{"d2:i8i128ee", "i8", "integer 128"},
+		{"d4:Hash3:abce", "Hash", "3-byte string"},
+		{"d3:Key5:abcdee", "Key", "5-byte string"},
+		{"d6:Octetsli1eee", "Octets", "list"},
+		{"d3:Mapd1:ali1eeee", "Map.a", "list"},
+		{"d6:Matrixlli1ei2ei3eeee", "", ""},
+		{"d6:Matrixli1eee", "Matrix[0]", "integer"},
+		{"d5:Filesd1:ad6:lengthdeeee", "Files.a.length", "dictionary"},
+		{"d4:Treed4:Treed3:Inti1.5eeee", "", "syntax"},
+	}
+	for _, tt := range tests {
+		var k Kitchen
+		err := k.UnmarshalBencode([]byte(tt.in))
+		var ute *bencode.UnmarshalTypeError
+		var se *bencode.SyntaxError
+		switch {
+		case tt.value == "syntax":
+			if !errors.As(err, &se) {
+				t.Errorf("UnmarshalBencode(%q) = %v, want a SyntaxError", tt.in, err)
+			}
+		case tt.value == "":
+			if err != nil {
+				t.Errorf("UnmarshalBencode(%q) = %v", tt.in, err)
+			}
+		case !errors.As(err, &ute) || ute.Field != tt.field || ute.Value != tt.value:
+			t.Errorf("UnmarshalBencode(%q) = %v, want %s at %s", tt.in, err, tt.value, tt.field)
+		}
+	}
+
+	var m Message
+	if err := m.UnmarshalBencode([]byte("d1:t1:aei1e")); err == nil {
+		t.Error("UnmarshalBencode accepted trailing data")
+	}
+	if err := m.UnmarshalBencode([]byte("le")); !errors.As(err, &ute) || ute.Value != "list" {
+		t.Errorf("UnmarshalBencode of a list = %v", err)
+	}
+}
+
+func TestOctets(t *testing.T) {
+	// Slices and arrays of types defined from byte are strings, as they
+	// are for Marshal.
+	k := Kitchen{Octets: []Octet("hi"), Key: [4]Octet{'a', 'b', 'c', 'd'}}
+	got, err := k.MarshalBencode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(got, []byte("3:Key4:abcd")) || !bytes.Contains(got, []byte("6:Octets2:hi")) {
+		t.Errorf("MarshalBencode = %q", got)
+	}
+	var y Kitchen
+	if err := y.UnmarshalBencode(got); err != nil {
+		t.Fatal(err)
+	}
+	if string(y.Octets) != "hi" || y.Key != k.Key {
+		t.Errorf("UnmarshalBencode(%q) = %q, %q", got, y.Octets, y.Key)
+	}
+}
+
+func TestAppendBencodeAllocations(t *testing.T) {
+	m := Message{T: "aa", Y: "r", R: &Reply{Nodes: make([]byte, 26*8), Token: "token"}}
+	buf := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = m.AppendBencode(buf[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("AppendBencode made %v allocations", allocs)
+	}
+	want, _ := bencode.MarshalBytes((*bencodegenPlainMessage)(&m))
+	if !bytes.Equal(buf, want) {
+		t.Errorf("AppendBencode = %q, want %q", buf, want)
+	}
+}
+
+var benchTorrent = Torrent{
+	Announce:     "udp://tracker.publicbt.com:80/announce",
+	AnnounceList: [][]string{{"udp://tracker.publicbt.com:80/announce"}, {"udp://tracker.openbittorrent.com:80/announce"}},
+	Comment:      "Debian CD from cdimage.debian.org",
+	Info: Info{
+		Name:        "debian-8.8.0-arm64-netinst.iso",
+		PieceLength: 262144,
+		Pieces:      make([]byte, 20*652),
+		Files:       []File{{Length: 170917888, Path: []string{"debian", "debian-8.8.0-arm64-netinst.iso"}}},
+	},
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	b.Run("Generated", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf []byte
+		for i := 0; i < b.N; i++ {
+			buf, _ = benchTorrent.AppendBencode(buf[:0])
+		}
+	})
+	b.Run("Reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf []byte
+		for i := 0; i < b.N; i++ {
+			buf, _ = bencode.AppendMarshal(buf[:0], (*bencodegenPlainTorrent)(&benchTorrent))
+		}
+	})
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	data, err := benchTorrent.MarshalBencode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var t Torrent
+			if err := t.UnmarshalBencode(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var t bencodegenPlainTorrent
+			if err := bencode.UnmarshalBytes(data, &t); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
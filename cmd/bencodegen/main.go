@@ -0,0 +1,110 @@
+// Bencodegen generates methods that encode and decode struct types as
+// bencode without reflection. Given the name of a struct type T, it writes
+// the methods
+//
+//	func (x *T) AppendBencode(b []byte) ([]byte, error)
+//	func (x T) MarshalBencode() ([]byte, error)
+//	func (x *T) UnmarshalBencode(data []byte) error
+//
+// so that T implements bencode.Marshaler and bencode.Unmarshaler, and
+// Marshal and Unmarshal use the generated code for it wherever it
+// appears. Dictionary keys are taken from the struct tags in the same way
+// as by Marshal, including the omitempty option and "-", and
+// AppendBencode produces exactly the output of Marshal.
+//
+// Typically bencodegen is run by go generate, with a directive such as
+//
+//	//go:generate go run github.com/jackpal/bencode-go/cmd/bencodegen -type Message,Reply
+//
+// in the package defining the types. By default the methods are written to
+// bencode_gen.go, and tests checking them against Marshal and Unmarshal to
+// bencode_gen_test.go. The helper functions they share are written to
+// bencodegen_support.go and bencodegen_support_test.go, which are the same
+// for every run, so a package may have several go:generate directives
+// with different output files.
+//
+// Fields of the listed types, and slices, arrays, maps and pointers of
+// them, are encoded by the generated code. So are booleans, integers,
+// strings and byte slices and arrays. Other fields, such as interfaces,
+// floating point numbers, RawMessage and types with their own
+// MarshalBencode method, are passed to AppendMarshal and
+// Decoder.DecodeInto. Struct types with promoted fields of embedded
+// structs are not supported.
+//
+// The generated UnmarshalBencode reads its input with Decoder.Token, so
+// unlike Unmarshal it rejects integers written with a fraction or an
+// exponent.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
+	output    = flag.String("output", "", "output file name; default srcdir/bencode_gen.go")
+	tests     = flag.Bool("tests", true, "write round-trip tests to the output file name with _test.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of bencodegen:\n")
+	fmt.Fprintf(os.Stderr, "\tbencodegen [flags] -type T [directory]\n")
+	fmt.Fprintf(os.Stderr, "For more information, see:\n")
+	fmt.Fprintf(os.Stderr, "\thttps://pkg.go.dev/github.com/jackpal/bencode-go/cmd/bencodegen\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("bencodegen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if len(*typeNames) == 0 || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	outName := *output
+	if outName == "" {
+		outName = filepath.Join(dir, "bencode_gen.go")
+	}
+
+	args := []string{"-type", *typeNames}
+	if *output != "" {
+		args = append(args, "-output", filepath.Base(*output))
+	}
+	g, err := load(dir, filepath.Base(outName), strings.Split(*typeNames, ","))
+	if err != nil {
+		log.Fatal(err)
+	}
+	g.command = "bencodegen " + strings.Join(args, " ")
+
+	outDir := filepath.Dir(outName)
+	files := map[string]func() ([]byte, error){
+		outName:                            g.generate,
+		filepath.Join(outDir, supportFile): g.generateSupport,
+	}
+	if *tests {
+		testName := strings.TrimSuffix(outName, ".go") + "_test.go"
+		files[testName] = g.generateTests
+		files[filepath.Join(outDir, testSupportFile)] = g.generateTestSupport
+	}
+	for name, gen := range files {
+		src, err := gen()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(name, src, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+// supportSource holds the helper functions that the generated methods
+// call, which are written to supportFile.
+const supportSource = `
+var bencodegenErrNilPointer = errors.New("Can't write nil pointer")
+
+func bencodegenAppendInt(b []byte, i int64) []byte {
+	b = append(b, 'i')
+	b = strconv.AppendInt(b, i, 10)
+	return append(b, 'e')
+}
+
+func bencodegenAppendUint(b []byte, u uint64) []byte {
+	b = append(b, 'i')
+	b = strconv.AppendUint(b, u, 10)
+	return append(b, 'e')
+}
+
+func bencodegenAppendString(b []byte, s string) []byte {
+	b = strconv.AppendInt(b, int64(len(s)), 10)
+	b = append(b, ':')
+	return append(b, s...)
+}
+
+func bencodegenAppendBytes(b, s []byte) []byte {
+	b = strconv.AppendInt(b, int64(len(s)), 10)
+	b = append(b, ':')
+	return append(b, s...)
+}
+
+// bencodegenAppendByteElems is bencodegenAppendBytes for slices of types
+// defined from byte, which cannot be converted to []byte.
+func bencodegenAppendByteElems[E ~byte](b []byte, s []E) []byte {
+	b = strconv.AppendInt(b, int64(len(s)), 10)
+	b = append(b, ':')
+	for _, c := range s {
+		b = append(b, byte(c))
+	}
+	return b
+}
+
+func bencodegenSortedKeys[M ~map[K]V, K ~string, V any](m M) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// bencodegenUnmarshal decodes data, which must hold exactly one value,
+// with decode.
+func bencodegenUnmarshal(data []byte, decode func(*bencode.Decoder) error) error {
+	d := bencode.NewBytesDecoder(data)
+	err := decode(d)
+	if _, ok := err.(*bencode.UnmarshalTypeError); err != nil && !ok {
+		return err
+	}
+	if d.More() {
+		return errors.New("bencode: unexpected data after top-level value")
+	}
+	return err
+}
+
+// bencodegenKeep records err as the first error, if it is an
+// UnmarshalTypeError, giving it the path to the value. Decoding continues
+// after such errors, as it does in Unmarshal. Other errors are returned.
+func bencodegenKeep(first *error, err error, path string) error {
+	ute, ok := err.(*bencode.UnmarshalTypeError)
+	if !ok {
+		return err
+	}
+	switch {
+	case ute.Field == "":
+		ute.Field = path
+	case ute.Field[0] == '[':
+		ute.Field = path + ute.Field
+	default:
+		ute.Field = path + "." + ute.Field
+	}
+	if *first == nil {
+		*first = ute
+	}
+	return nil
+}
+
+// bencodegenMismatch skips the rest of a value, starting with tok at
+// offset off, that does not fit the Go value p points to, and reports it.
+func bencodegenMismatch(d *bencode.Decoder, tok bencode.Token, off int64, p any) error {
+	var value string
+	switch tok.Kind {
+	case bencode.Int:
+		value = "integer"
+	case bencode.Bytes:
+		value = "string"
+	case bencode.ListStart, bencode.DictStart:
+		value = "list"
+		if tok.Kind == bencode.DictStart {
+			value = "dictionary"
+		}
+		for d.More() {
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+		if err := bencodegenEnd(d); err != nil {
+			return err
+		}
+	default:
+		return errors.New("bencode: unexpected " + tok.Kind.String())
+	}
+	return &bencode.UnmarshalTypeError{Value: value, Type: reflect.TypeOf(p).Elem(), Offset: off}
+}
+
+func bencodegenRangeError(tok bencode.Token, off int64, p any) error {
+	return &bencode.UnmarshalTypeError{Value: "integer " + string(tok.Value), Type: reflect.TypeOf(p).Elem(), Offset: off}
+}
+
+// bencodegenBegin reads the start of a list or dictionary.
+func bencodegenBegin(d *bencode.Decoder, kind bencode.TokenKind, p any) error {
+	off := d.InputOffset()
+	tok, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if tok.Kind != kind {
+		return bencodegenMismatch(d, tok, off, p)
+	}
+	return nil
+}
+
+// bencodegenEnd reads the end of a list or dictionary, once More has
+// reported that there are no more values.
+func bencodegenEnd(d *bencode.Decoder) error {
+	tok, err := d.Token()
+	if err == nil && tok.Kind != bencode.End {
+		err = errors.New("bencode: expected the end of a list or dictionary")
+	}
+	return err
+}
+
+func bencodegenKey(d *bencode.Decoder) (string, error) {
+	tok, err := d.Token()
+	return string(tok.Value), err
+}
+
+func bencodegenFold(key []byte) string {
+	return strings.ToLower(string(key))
+}
+
+// bencodegenToken reads a token of the given kind.
+func bencodegenToken(d *bencode.Decoder, kind bencode.TokenKind, p any) (bencode.Token, int64, error) {
+	off := d.InputOffset()
+	tok, err := d.Token()
+	if err == nil && tok.Kind != kind {
+		err = bencodegenMismatch(d, tok, off, p)
+	}
+	return tok, off, err
+}
+
+func bencodegenInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](d *bencode.Decoder, p *T) error {
+	tok, off, err := bencodegenToken(d, bencode.Int, p)
+	if err != nil {
+		return err
+	}
+	i, err := tok.Int64()
+	if v := T(i); err == nil && int64(v) == i {
+		*p = v
+		return nil
+	}
+	return bencodegenRangeError(tok, off, p)
+}
+
+func bencodegenUint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr](d *bencode.Decoder, p *T) error {
+	tok, off, err := bencodegenToken(d, bencode.Int, p)
+	if err != nil {
+		return err
+	}
+	var u uint64
+	ok := true
+	for _, c := range tok.Value {
+		if c == '-' {
+			// Of the negative numbers, only -0 fits.
+			continue
+		}
+		digit := uint64(c - '0')
+		if u > (1<<64-1-digit)/10 {
+			ok = false
+			break
+		}
+		u = u*10 + digit
+	}
+	if tok.Value[0] == '-' && u != 0 {
+		ok = false
+	}
+	if v := T(u); ok && uint64(v) == u {
+		*p = v
+		return nil
+	}
+	return bencodegenRangeError(tok, off, p)
+}
+
+func bencodegenBool[T ~bool](d *bencode.Decoder, p *T) error {
+	tok, _, err := bencodegenToken(d, bencode.Int, p)
+	if err != nil {
+		return err
+	}
+	nonZero := false
+	for _, c := range tok.Value {
+		nonZero = nonZero || c != '-' && c != '0'
+	}
+	*p = T(nonZero)
+	return nil
+}
+
+func bencodegenString[T ~string](d *bencode.Decoder, p *T) error {
+	tok, _, err := bencodegenToken(d, bencode.Bytes, p)
+	if err != nil {
+		return err
+	}
+	*p = T(tok.Value)
+	return nil
+}
+
+func bencodegenBytes[T ~[]byte](d *bencode.Decoder, p *T) error {
+	tok, _, err := bencodegenToken(d, bencode.Bytes, p)
+	if err != nil {
+		return err
+	}
+	*p = T(bytes.Clone(tok.Value))
+	return nil
+}
+
+// bencodegenByteElems is bencodegenBytes for slices of types defined from
+// byte.
+func bencodegenByteElems[S ~[]E, E ~byte](d *bencode.Decoder, p *S) error {
+	tok, _, err := bencodegenToken(d, bencode.Bytes, p)
+	if err != nil {
+		return err
+	}
+	if tok.Value == nil {
+		*p = nil
+		return nil
+	}
+	s := make(S, len(tok.Value))
+	for i, c := range tok.Value {
+		s[i] = E(c)
+	}
+	*p = s
+	return nil
+}
+
+func bencodegenByteArray[E ~byte](d *bencode.Decoder, dst []E, p any) error {
+	tok, off, err := bencodegenToken(d, bencode.Bytes, p)
+	if err != nil {
+		return err
+	}
+	if len(tok.Value) != len(dst) {
+		return &bencode.UnmarshalTypeError{Value: strconv.Itoa(len(tok.Value)) + "-byte string", Type: reflect.TypeOf(p).Elem(), Offset: off}
+	}
+	for i, c := range tok.Value {
+		dst[i] = E(c)
+	}
+	return nil
+}
+
+// bencodegenGrow makes room for element i of s, which is zero if it is
+// new.
+func bencodegenGrow[S ~[]E, E any](s S, i int) S {
+	if i < len(s) {
+		return s
+	}
+	var zero E
+	return append(s, zero)
+}
+
+// bencodegenTrim cuts s to the n elements decoded. A decoded list is
+// never nil.
+func bencodegenTrim[S ~[]E, E any](s S, n int) S {
+	s = s[:n]
+	if s == nil {
+		s = S{}
+	}
+	return s
+}
+
+func bencodegenMakeMap[M ~map[K]V, K comparable, V any](m *M) {
+	if *m == nil {
+		*m = make(M)
+	}
+}
+
+func bencodegenElem[M ~map[K]V, K comparable, V any](M) V {
+	var zero V
+	return zero
+}
+
+func bencodegenMapSet[M ~map[K]V, K ~string, V any](m M, k string, v V) {
+	m[K(k)] = v
+}
+
+func bencodegenAlloc[P ~*T, T any](p *P) P {
+	if *p == nil {
+		*p = new(T)
+	}
+	return *p
+}
+`
+
+// testTemplate is the round-trip test of one type, whose name replaces
+// the %[1]s verbs.
+const testTemplate = `
+// bencodegenPlain%[1]s has the fields of %[1]s without its methods, so
+// that Marshal and Unmarshal handle it by reflection.
+type bencodegenPlain%[1]s %[1]s
+
+func TestBencodegen%[1]s(t *testing.T) {
+	for seed := 0; seed < 8; seed++ {
+		var x %[1]s
+		bencodegenFill(reflect.ValueOf(&x).Elem(), seed, 0)
+		want, wantErr := bencode.MarshalBytes((*bencodegenPlain%[1]s)(&x))
+		got, err := x.MarshalBencode()
+		if !bytes.Equal(got, want) || (err == nil) != (wantErr == nil) {
+			t.Fatalf("seed %%d: MarshalBencode = %%q, %%v; Marshal = %%q, %%v", seed, got, err, want, wantErr)
+		}
+		if wantErr != nil {
+			continue
+		}
+		if got, err := x.AppendBencode([]byte("prefix")); err != nil || string(got) != "prefix"+string(want) {
+			t.Fatalf("seed %%d: AppendBencode = %%q, %%v", seed, got, err)
+		}
+
+		var y, z %[1]s
+		err = y.UnmarshalBencode(want)
+		wantErr = bencode.UnmarshalBytes(want, (*bencodegenPlain%[1]s)(&z))
+		if !reflect.DeepEqual(y, z) || (err == nil) != (wantErr == nil) {
+			t.Fatalf("seed %%d: UnmarshalBencode(%%q) = %%+v, %%v; Unmarshal = %%+v, %%v", seed, want, y, err, z, wantErr)
+		}
+	}
+}
+`
+
+// testSupportSource holds the helper functions of the generated tests,
+// which are written to testSupportFile.
+const testSupportSource = `
+// bencodegenFill fills v with values derived from seed.
+func bencodegenFill(v reflect.Value, seed, depth int) {
+	if depth > 4 || !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(seed%2 == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(seed - seed%2*2*seed))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(uint64(seed))
+	case reflect.String:
+		if v.Type() == reflect.TypeOf(bencode.Number("")) {
+			v.SetString(strconv.Itoa(seed))
+			return
+		}
+		v.SetString(strings.Repeat("ab", seed%4))
+	case reflect.Slice:
+		if v.Type() == reflect.TypeOf(bencode.RawMessage(nil)) {
+			if seed%2 == 1 {
+				v.SetBytes([]byte("i" + strconv.Itoa(seed) + "e"))
+			}
+			return
+		}
+		n := seed % 3
+		if n == 0 && seed%2 == 0 {
+			return
+		}
+		// Elements get odd seeds, so that they are not nil pointers,
+		// which cannot be encoded in lists.
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			bencodegenFill(s.Index(i), 2*(seed+i)+1, depth+1)
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			bencodegenFill(v.Index(i), 2*(seed+i)+1, depth+1)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		n := seed % 3
+		if n == 0 && seed%2 == 0 {
+			return
+		}
+		m := reflect.MakeMapWithSize(v.Type(), n)
+		for i := 0; i < n; i++ {
+			k := reflect.New(v.Type().Key()).Elem()
+			k.SetString("k" + strconv.Itoa(n-i))
+			e := reflect.New(v.Type().Elem()).Elem()
+			bencodegenFill(e, seed+i+1, depth+1)
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(strings.Repeat("ab", seed%4)))
+		}
+	case reflect.Ptr:
+		if seed%2 == 0 {
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		bencodegenFill(p.Elem(), seed, depth+1)
+		v.Set(p)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			bencodegenFill(v.Field(i), seed+i, depth+1)
+		}
+	}
+}
+`
@@ -9,7 +9,10 @@ package bencode
 
 import (
 	"bufio"
+	"bytes"
 	"io"
+	"slices"
+	"strconv"
 )
 
 // Decode a bencode stream
@@ -17,19 +20,387 @@ import (
 // Decode parses the stream r and returns the
 // generic bencode object representation.  The object representation is a tree
 // of Go data types.  The data return value may be one of string,
-// int64, uint64, []interface{} or map[string]interface{}.  The slice and map
+// int64, *big.Int, []interface{} or map[string]interface{}.  The slice and map
 // elements may in turn contain any of the types listed above and so on.
+// Integers that do not fit in an int64 are returned as *big.Int.
+// A Decoder can be configured to return []byte instead of string, and
+// Number instead of int64 and *big.Int.
 //
-// If Decode encounters a syntax error, it returns with err set to an
-// instance of Error.
+// If Decode encounters a syntax error, it returns with err set to a
+// *SyntaxError giving the offset of the offending byte. Input that ends
+// inside the value is reported as io.ErrUnexpectedEOF.
+//
+// Decode reads exactly one value and never consumes bytes from reader beyond
+// its end. To read a sequence of values from one stream, use a Decoder.
+// To get results of a known type without type assertions, use UnmarshalAs,
+// DecodeList or Get.
 func Decode(reader io.Reader) (data interface{}, err error) {
-	// Check to see if the reader already fulfills the bufio.Reader interface.
-	// Wrap it in a bufio.Reader if it doesn't.
-	bufioReader, ok := reader.(*bufio.Reader)
+	return DefaultLimits.Decode(reader)
+}
+
+// Decode is like the package level Decode, but applies the limits l
+// rather than DefaultLimits.
+func (l Limits) Decode(reader io.Reader) (data interface{}, err error) {
+	d := decodeState{opts: &decodeOptions{limits: l}}
+	vr := d.readFrom(reader)
+	data, err = d.decode()
+	return data, vr.done(err)
+}
+
+// DecodeBytes is like Decode, but parses the value held in data directly,
+// without the copy through a bufio.Reader. It is an error for data to hold
+// anything after the value. To decode a sequence of values held in memory,
+// or to have []byte results alias data rather than copy it, use
+// NewBytesDecoder.
+func DecodeBytes(data []byte) (interface{}, error) {
+	return DefaultLimits.DecodeBytes(data)
+}
+
+// DecodeBytes is like the package level DecodeBytes, but applies the
+// limits l rather than DefaultLimits.
+func (l Limits) DecodeBytes(data []byte) (interface{}, error) {
+	d := decodeState{data: data, opts: &decodeOptions{limits: l}}
+	v, err := d.decode()
+	if err == nil {
+		err = d.checkEnd()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// A decodeState decodes bencode values, either from r or, if r is nil,
+// from the in-memory data starting at off. Generic values are built by
+// decode, Go values are filled in by unmarshal.
+type decodeState struct {
+	r    *bufio.Reader
+	data []byte
+	off  int
+	opts *decodeOptions
+
+	// read counts the bytes read from r, start is the position of the
+	// value being decoded and depth its current nesting.
+	read  int64
+	start int64
+	depth int
+
+	// While capturing, the bytes read from r are appended to raw.
+	capturing bool
+	raw       []byte
+
+	// scratch holds tokens that do not fit in r's buffer, fold holds
+	// case-folded dictionary keys.
+	scratch []byte
+	fold    []byte
+
+	// path locates the value being unmarshalled in the input, valueOff
+	// is its offset, and savedErr holds the first error that does not
+	// stop unmarshalling, returned once the value is complete.
+	path     []pathElem
+	valueOff int64
+	savedErr error
+
+	// tokens holds the lists and dictionaries entered by Decoder.Token.
+	tokens []tokenFrame
+}
+
+// begin prepares to decode a top-level value, or the next value inside the
+// lists and dictionaries entered by Decoder.Token. It returns io.EOF if
+// there is no input left.
+func (d *decodeState) begin() error {
+	d.path = d.path[:0]
+	d.savedErr = nil
+	if len(d.tokens) > 0 {
+		return d.beginInFrame()
+	}
+	d.start = d.pos()
+	d.depth = 0
+	if d.r != nil {
+		_, err := d.r.Peek(1)
+		return err
+	}
+	if d.off >= len(d.data) {
+		return io.EOF
+	}
+	return nil
+}
+
+// pos returns the number of bytes of input consumed.
+func (d *decodeState) pos() int64 {
+	if d.r != nil {
+		return d.read
+	}
+	return int64(d.off)
+}
+
+// checkEnd returns an error if in-memory input is left after a value.
+func (d *decodeState) checkEnd() error {
+	if d.off < len(d.data) {
+		return syntaxError(d.off, d.data[d.off], "end of input")
+	}
+	return nil
+}
+
+// alias reports whether []byte results may share memory with the input.
+func (d *decodeState) alias() bool {
+	return d.r == nil && d.opts.alias
+}
+
+// unexpectedEOF converts io.EOF, met in the middle of a value, to
+// io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// consumed accounts for bytes read from r.
+func (d *decodeState) consumed(buf []byte) {
+	d.read += int64(len(buf))
+	if d.capturing {
+		d.raw = append(d.raw, buf...)
+	}
+}
+
+func (d *decodeState) readByte() (byte, error) {
+	if d.r != nil {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		d.read++
+		if d.capturing {
+			d.raw = append(d.raw, c)
+		}
+		return c, nil
+	}
+	if d.off >= len(d.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	c := d.data[d.off]
+	d.off++
+	return c, nil
+}
+
+func (d *decodeState) unreadByte() error {
+	if d.r != nil {
+		if err := d.r.UnreadByte(); err != nil {
+			return err
+		}
+		d.read--
+		if d.capturing {
+			d.raw = d.raw[:len(d.raw)-1]
+		}
+		return nil
+	}
+	d.off--
+	return nil
+}
+
+// readUntil reads up to and including delim and returns the bytes before
+// it. Like optimisticReadBytes it returns a slice of the buffered or
+// in-memory input when possible, so the result is only valid until the
+// next read.
+func (d *decodeState) readUntil(delim byte) ([]byte, error) {
+	if d.r == nil {
+		i := bytes.IndexByte(d.data[d.off:], delim)
+		if i < 0 {
+			d.off = len(d.data)
+			return nil, io.ErrUnexpectedEOF
+		}
+		buf := d.data[d.off : d.off+i]
+		d.off += i + 1
+		return buf, d.checkSize(0)
+	}
+	buf, err := d.r.ReadSlice(delim)
+	if err == bufio.ErrBufferFull {
+		d.scratch = append(d.scratch[:0], buf...)
+		for err == bufio.ErrBufferFull {
+			if err = d.checkSize(len(d.scratch)); err != nil {
+				return nil, err
+			}
+			buf, err = d.r.ReadSlice(delim)
+			d.scratch = append(d.scratch, buf...)
+		}
+		buf = d.scratch
+	}
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	d.consumed(buf)
+	return buf[:len(buf)-1], d.checkSize(0)
+}
+
+// readLength reads the length of a string, up to and including the ':'.
+func (d *decodeState) readLength() (int, error) {
+	buf, err := d.readUntil(':')
+	if err != nil {
+		return 0, err
+	}
+	if d.opts.strict {
+		if err = checkCanonicalLength(buf); err != nil {
+			return 0, d.tokenError(err, buf)
+		}
+	}
+	length, ok := parseInteger(buf)
 	if !ok {
-		bufioReader = newBufioReader(reader)
-		defer bufioReaderPool.Put(bufioReader)
+		if length, err = strconv.ParseInt(string(buf), 10, 64); err != nil {
+			return 0, d.tokenError(badLength(buf), buf)
+		}
+	}
+	if length < 0 || int64(int(length)) != length {
+		return 0, d.tokenError(badLength(buf), buf)
+	}
+	return int(length), d.checkStringLen(int(length))
+}
+
+// tokenError rebases err, found in buf, which must be the token just read
+// by readUntil.
+func (d *decodeState) tokenError(err error, buf []byte) error {
+	return rebase(err, d.pos()-1-int64(len(buf)))
+}
+
+// unexpected returns the error for c, the byte just read.
+func (d *decodeState) unexpected(c byte, expected string) error {
+	return &SyntaxError{d.pos() - 1, c, expected}
+}
+
+// checkKeyOrder checks the order of key, the dictionary key just read.
+func (d *decodeState) checkKeyOrder(first bool, prev, key []byte) error {
+	return rebase(checkKeyOrder(first, prev, key), d.pos()-int64(len(key)))
+}
+
+// readInteger reads the text of an integer whose 'i' has been read, up to
+// and including the 'e'. It checks that the integer is canonical in strict
+// mode, and otherwise that it is an integer or a floating point number.
+func (d *decodeState) readInteger() ([]byte, error) {
+	buf, err := d.readUntil('e')
+	if err != nil {
+		return nil, err
 	}
+	if d.opts.strict {
+		err = checkCanonicalInt(buf)
+	} else if !validInteger(buf) {
+		err = badInteger(buf)
+	}
+	if err != nil {
+		return nil, d.tokenError(err, buf)
+	}
+	return buf, nil
+}
 
-	return decodeFromReader(bufioReader)
+// parseInteger returns the value of buf if it is a decimal integer of at
+// most 18 digits, which covers nearly all lengths and integers, without
+// the cost of converting it to a string for strconv.
+func parseInteger(buf []byte) (int64, bool) {
+	digits := buf
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || len(digits) > 18 {
+		return 0, false
+	}
+	var n int64
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	if len(digits) < len(buf) {
+		n = -n
+	}
+	return n, true
+}
+
+// readString reads a string and returns its contents. They are only valid
+// until the next read, unless the input is in memory, in which case they
+// are a slice of it.
+func (d *decodeState) readString() ([]byte, error) {
+	length, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	if d.r == nil {
+		if length > len(d.data)-d.off {
+			d.off = len(d.data)
+			return nil, io.ErrUnexpectedEOF
+		}
+		buf := d.data[d.off : d.off+length : d.off+length]
+		d.off += length
+		return buf, nil
+	}
+	if length <= d.r.Size() {
+		buf, err := d.r.Peek(length)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		d.r.Discard(length)
+		d.consumed(buf)
+		return buf, nil
+	}
+	// Grow the buffer as the bytes arrive rather than trusting the
+	// length up front.
+	d.scratch = d.scratch[:0]
+	for len(d.scratch) < length {
+		n := len(d.scratch)
+		chunk := min(length-n, max(n, d.r.Size()))
+		d.scratch = slices.Grow(d.scratch, chunk)[:n+chunk]
+		if _, err = readFull(d.r, d.scratch[n:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+	d.consumed(d.scratch)
+	return d.scratch, nil
+}
+
+// readKey reads a dictionary key, which is only valid until the next read.
+func (d *decodeState) readKey() ([]byte, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if c < '0' || c > '9' {
+		return nil, d.unexpected(c, expectedKey)
+	}
+	if err = d.unreadByte(); err != nil {
+		return nil, err
+	}
+	return d.readString()
+}
+
+// atEnd reports whether the next byte is the 'e' ending a list or
+// dictionary, consuming it if so.
+func (d *decodeState) atEnd() (bool, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return false, err
+	}
+	if c == 'e' {
+		return true, nil
+	}
+	return false, d.unreadByte()
+}
+
+// readRaw reads the next value verbatim, checking it as skip does. The
+// result is only valid until the next read, unless the input is in memory,
+// in which case it is a slice of it.
+func (d *decodeState) readRaw() ([]byte, error) {
+	if d.r == nil {
+		start := d.off
+		if err := d.skip(); err != nil {
+			return nil, err
+		}
+		return d.data[start:d.off:d.off], nil
+	}
+	d.capturing = true
+	d.raw = d.raw[:0]
+	err := d.skip()
+	d.capturing = false
+	if err != nil {
+		return nil, err
+	}
+	return d.raw, nil
 }
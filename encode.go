@@ -0,0 +1,605 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoding
+//
+// Each Go type is compiled once into an encoderFunc, which is cached for
+// the life of the program. Struct encoders hold their fields already sorted
+// by key, with each key pre-encoded, so encoding a struct involves no tag
+// parsing, sorting or allocation.
+
+// An encodeState accumulates the encoding of a value in memory, so that it
+// can be handed to the destination io.Writer in a single call.
+type encodeState struct {
+	buf []byte
+	encodeOptions
+
+	// ptrSeen holds the maps, slices and pointers currently being
+	// written, when cycle detection is enabled.
+	ptrSeen map[ptrKey]struct{}
+
+	// mapEntries is scratch space for sorting map keys, shared by
+	// nested maps as a stack.
+	mapEntries []mapEntry
+}
+
+type encodeOptions struct {
+	canonical    bool
+	floatPolicy  FloatPolicy
+	detectCycles bool
+}
+
+var encodeStatePool sync.Pool
+
+var byteType = reflect.TypeOf(byte(0))
+
+func newEncodeState() *encodeState {
+	if v := encodeStatePool.Get(); v != nil {
+		e := v.(*encodeState)
+		e.buf = e.buf[:0]
+		return e
+	}
+	return new(encodeState)
+}
+
+// maxPooledBuffer is the capacity beyond which an encodeState is not
+// returned to the pool, so that encoding one huge value does not pin its
+// buffer for the life of the program.
+const maxPooledBuffer = 64 << 10
+
+func freeEncodeState(e *encodeState) {
+	if cap(e.buf) > maxPooledBuffer {
+		return
+	}
+	encodeStatePool.Put(e)
+}
+
+func (e *encodeState) writeString(s string) {
+	e.buf = strconv.AppendInt(e.buf, int64(len(s)), 10)
+	e.buf = append(e.buf, ':')
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encodeState) writeBytes(b []byte) {
+	e.buf = strconv.AppendInt(e.buf, int64(len(b)), 10)
+	e.buf = append(e.buf, ':')
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encodeState) writeByteArray(v reflect.Value) {
+	n := v.Len()
+	e.buf = strconv.AppendInt(e.buf, int64(n), 10)
+	e.buf = append(e.buf, ':')
+	start := len(e.buf)
+	e.buf = slices.Grow(e.buf, n)[:start+n]
+	if v.Type().Elem() == byteType {
+		reflect.Copy(reflect.ValueOf(e.buf[start:]), v)
+		return
+	}
+	// reflect.Copy does not convert from named byte types.
+	for i := 0; i < n; i++ {
+		e.buf[start+i] = byte(v.Index(i).Uint())
+	}
+}
+
+func (e *encodeState) writeInt(i int64) {
+	e.buf = append(e.buf, 'i')
+	e.buf = strconv.AppendInt(e.buf, i, 10)
+	e.buf = append(e.buf, 'e')
+}
+
+func (e *encodeState) writeUint(i uint64) {
+	e.buf = append(e.buf, 'i')
+	e.buf = strconv.AppendUint(e.buf, i, 10)
+	e.buf = append(e.buf, 'e')
+}
+
+// FloatPolicy controls how an Encoder writes floating point values, which
+// have no representation in bencode.
+type FloatPolicy int
+
+const (
+	// FloatError causes floating point values to be rejected with a
+	// MarshalError. This is the default, and the behavior of Marshal.
+	FloatError FloatPolicy = iota
+
+	// FloatTruncate writes floating point values as integers, truncating
+	// toward zero. NaN, infinities and values outside the int64 range
+	// are rejected.
+	FloatTruncate
+
+	// FloatString writes floating point values as strings holding the
+	// shortest decimal representation that round-trips, such as "0.5".
+	FloatString
+)
+
+func (e *encodeState) writeFloat(v reflect.Value) error {
+	f := v.Float()
+	switch e.floatPolicy {
+	case FloatTruncate:
+		if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return &MarshalError{v.Type()}
+		}
+		e.writeInt(int64(f))
+		return nil
+	case FloatString:
+		var scratch [32]byte
+		e.writeBytes(strconv.AppendFloat(scratch[:0], f, 'g', -1, v.Type().Bits()))
+		return nil
+	}
+	return &MarshalError{v.Type()}
+}
+
+// A ptrKey identifies a map, slice or pointer for cycle detection. The type
+// is included because a pointer to a struct and a pointer to its first
+// field share an address.
+type ptrKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// enter records that the map, slice or pointer v is being written, and
+// reports an error if it is already being written further up the stack.
+func (e *encodeState) enter(v reflect.Value) (key ptrKey, err error) {
+	key = ptrKey{typ: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if _, ok := e.ptrSeen[key]; ok {
+		return key, fmt.Errorf("bencode: encountered a cycle via %s", v.Type())
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = make(map[ptrKey]struct{})
+	}
+	e.ptrSeen[key] = struct{}{}
+	return key, nil
+}
+
+func (e *encodeState) writeMarshaler(v reflect.Value) error {
+	data, err := v.Interface().(Marshaler).MarshalBencode()
+	if err == nil {
+		err = checkValid(data, e.canonical)
+	}
+	if err != nil {
+		return &MarshalerError{v.Type(), err}
+	}
+	e.buf = append(e.buf, data...)
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// An encoderFunc appends the encoding of v, whose type is the type the
+// function was built for, to e.buf.
+type encoderFunc func(e *encodeState, v reflect.Value) error
+
+var encoderCache sync.Map // map[reflect.Type]encoderFunc
+
+// writeValue appends the encoding of val to e.buf.
+func (e *encodeState) writeValue(val reflect.Value) error {
+	if !val.IsValid() {
+		return errors.New("Can't write null value")
+	}
+	return typeEncoder(val.Type())(e, val)
+}
+
+// typeEncoder returns the cached encoder for t, building it if needed.
+func typeEncoder(t reflect.Type) encoderFunc {
+	if fi, ok := encoderCache.Load(t); ok {
+		return fi.(encoderFunc)
+	}
+
+	// To deal with recursive types, store an indirect func in the cache
+	// before building the real one. It waits for the real func, f, to be
+	// ready and then calls it. The indirect func is only used by the
+	// recursive parts of t itself.
+	var (
+		wg sync.WaitGroup
+		f  encoderFunc
+	)
+	wg.Add(1)
+	fi, loaded := encoderCache.LoadOrStore(t, encoderFunc(func(e *encodeState, v reflect.Value) error {
+		wg.Wait()
+		return f(e, v)
+	}))
+	if loaded {
+		return fi.(encoderFunc)
+	}
+
+	f = newTypeEncoder(t)
+	wg.Done()
+	encoderCache.Store(t, f)
+	return f
+}
+
+func newTypeEncoder(t reflect.Type) encoderFunc {
+	if t.Kind() != reflect.Interface {
+		if t.Implements(marshalerType) {
+			return marshalerEncoder
+		}
+		if t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(marshalerType) {
+			return addrMarshalerEncoder
+		}
+	}
+
+	switch t {
+	case numberType:
+		return numberEncoder
+	case bigIntType:
+		return bigIntEncoder
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolEncoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintEncoder
+	case reflect.Float32, reflect.Float64:
+		return floatEncoder
+	case reflect.String:
+		return stringEncoder
+	case reflect.Interface:
+		return interfaceEncoder
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return unsupportedTypeEncoder
+		}
+		return newMapEncoder(t)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// special case as byte-string
+			return bytesEncoder
+		}
+		return newSliceEncoder(t)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// special case as byte-string
+			return byteArrayEncoder
+		}
+		return newArrayEncoder(t)
+	case reflect.Ptr:
+		return newPtrEncoder(t)
+	}
+	return unsupportedTypeEncoder
+}
+
+func unsupportedTypeEncoder(e *encodeState, v reflect.Value) error {
+	return &MarshalError{v.Type()}
+}
+
+func marshalerEncoder(e *encodeState, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return errors.New("Can't write nil pointer")
+	}
+	return e.writeMarshaler(v)
+}
+
+func addrMarshalerEncoder(e *encodeState, v reflect.Value) error {
+	if !v.CanAddr() {
+		// Map elements and values passed to Marshal directly
+		// are not addressable, so use a copy.
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	return e.writeMarshaler(v.Addr())
+}
+
+func boolEncoder(e *encodeState, v reflect.Value) error {
+	if v.Bool() {
+		e.writeInt(1)
+	} else {
+		e.writeInt(0)
+	}
+	return nil
+}
+
+func intEncoder(e *encodeState, v reflect.Value) error {
+	e.writeInt(v.Int())
+	return nil
+}
+
+func uintEncoder(e *encodeState, v reflect.Value) error {
+	e.writeUint(v.Uint())
+	return nil
+}
+
+func floatEncoder(e *encodeState, v reflect.Value) error {
+	return e.writeFloat(v)
+}
+
+func stringEncoder(e *encodeState, v reflect.Value) error {
+	e.writeString(v.String())
+	return nil
+}
+
+// numberEncoder writes a Number as an integer. The empty Number is
+// written as 0.
+func numberEncoder(e *encodeState, v reflect.Value) error {
+	n := v.String()
+	if n == "" {
+		n = "0"
+	}
+	valid := isInteger([]byte(n))
+	if valid && e.canonical {
+		valid = checkCanonicalInt([]byte(n)) == nil
+	}
+	if !valid {
+		return fmt.Errorf("bencode: invalid Number %q", n)
+	}
+	e.buf = append(e.buf, 'i')
+	e.buf = append(e.buf, n...)
+	e.buf = append(e.buf, 'e')
+	return nil
+}
+
+func bigIntEncoder(e *encodeState, v reflect.Value) error {
+	if !v.CanAddr() {
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		v = c
+	}
+	e.buf = append(e.buf, 'i')
+	e.buf = v.Addr().Interface().(*big.Int).Append(e.buf, 10)
+	e.buf = append(e.buf, 'e')
+	return nil
+}
+
+func bytesEncoder(e *encodeState, v reflect.Value) error {
+	e.writeBytes(v.Bytes())
+	return nil
+}
+
+func byteArrayEncoder(e *encodeState, v reflect.Value) error {
+	e.writeByteArray(v)
+	return nil
+}
+
+func interfaceEncoder(e *encodeState, v reflect.Value) error {
+	if v.IsNil() {
+		return errors.New("Can't write null value")
+	}
+	v = v.Elem()
+	return typeEncoder(v.Type())(e, v)
+}
+
+// isNilValue reports whether v is left out when it appears as a
+// dictionary value: a nil interface, pointer or RawMessage, or a zero
+// Value.
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Slice:
+		return v.Type() == rawMessageType && v.IsNil()
+	case reflect.Struct:
+		return v.Type() == valueType && v.IsZero()
+	}
+	return false
+}
+
+type structEncoder struct {
+	fields []encodeField
+}
+
+// An encodeField is a struct field prepared for encoding.
+type encodeField struct {
+	name      string
+	key       []byte // the encoded dictionary key, such as "4:name"
+	index     []int
+	omitEmpty bool
+	enc       encoderFunc
+}
+
+func newStructEncoder(t reflect.Type) encoderFunc {
+	fields := typeFields(t)
+	se := structEncoder{fields: make([]encodeField, len(fields))}
+	for i, f := range fields {
+		ef := &se.fields[i]
+		ef.name = f.key
+		ef.key = strconv.AppendInt(nil, int64(len(f.key)), 10)
+		ef.key = append(ef.key, ':')
+		ef.key = append(ef.key, f.key...)
+		ef.index = f.index
+		ef.omitEmpty = f.omitEmpty
+		ef.enc = typeEncoder(f.typ)
+	}
+	slices.SortFunc(se.fields, func(a, b encodeField) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return se.encode
+}
+
+func (se structEncoder) encode(e *encodeState, v reflect.Value) error {
+	e.buf = append(e.buf, 'd')
+	for i := range se.fields {
+		f := &se.fields[i]
+		var fv reflect.Value
+		if len(f.index) == 1 {
+			fv = v.Field(f.index[0])
+		} else {
+			var ok bool
+			if fv, ok = fieldByIndex(v, f.index); !ok {
+				// Promoted through a nil embedded pointer.
+				continue
+			}
+		}
+		if isNilValue(fv) || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		e.buf = append(e.buf, f.key...)
+		if err := f.enc(e, fv); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, 'e')
+	return nil
+}
+
+type mapEncoder struct {
+	elemEnc encoderFunc
+}
+
+// A mapEntry is a map key and the index of its value in a slice of the
+// map's values, used to sort the entries of a map.
+type mapEntry struct {
+	key string
+	i   int
+}
+
+func newMapEncoder(t reflect.Type) encoderFunc {
+	me := mapEncoder{elemEnc: typeEncoder(t.Elem())}
+	return me.encode
+}
+
+func (me mapEncoder) encode(e *encodeState, v reflect.Value) (err error) {
+	n := v.Len()
+	if n == 0 {
+		e.buf = append(e.buf, 'd', 'e')
+		return nil
+	}
+	if e.detectCycles {
+		var seen ptrKey
+		if seen, err = e.enter(v); err != nil {
+			return
+		}
+		defer delete(e.ptrSeen, seen)
+	}
+
+	// Copy the keys and values out of the map, so that they can be
+	// sorted. The entries are kept on a stack in e that nested maps
+	// share, which saves allocating a slice for every map.
+	t := v.Type()
+	key := reflect.New(t.Key()).Elem()
+	vals := reflect.MakeSlice(reflect.SliceOf(t.Elem()), n, n)
+	start := len(e.mapEntries)
+	var iter reflect.MapIter
+	iter.Reset(v)
+	for i := 0; iter.Next(); i++ {
+		key.SetIterKey(&iter)
+		vals.Index(i).SetIterValue(&iter)
+		e.mapEntries = append(e.mapEntries, mapEntry{key.String(), i})
+	}
+	slices.SortFunc(e.mapEntries[start:], func(a, b mapEntry) int {
+		return strings.Compare(a.key, b.key)
+	})
+	defer func() {
+		clear(e.mapEntries[start:])
+		e.mapEntries = e.mapEntries[:start]
+	}()
+
+	e.buf = append(e.buf, 'd')
+	for i := start; i < start+n; i++ {
+		ent := e.mapEntries[i]
+		ev := vals.Index(ent.i)
+		if isNilValue(ev) {
+			continue // Skip null values
+		}
+		e.writeString(ent.key)
+		if err = me.elemEnc(e, ev); err != nil {
+			return
+		}
+	}
+	e.buf = append(e.buf, 'e')
+	return nil
+}
+
+type sliceEncoder struct {
+	arrayEnc encoderFunc
+}
+
+func newSliceEncoder(t reflect.Type) encoderFunc {
+	se := sliceEncoder{newArrayEncoder(t)}
+	return se.encode
+}
+
+func (se sliceEncoder) encode(e *encodeState, v reflect.Value) (err error) {
+	if e.detectCycles && v.Len() > 0 {
+		var seen ptrKey
+		if seen, err = e.enter(v); err != nil {
+			return
+		}
+		defer delete(e.ptrSeen, seen)
+	}
+	return se.arrayEnc(e, v)
+}
+
+type arrayEncoder struct {
+	elemEnc encoderFunc
+}
+
+func newArrayEncoder(t reflect.Type) encoderFunc {
+	ae := arrayEncoder{typeEncoder(t.Elem())}
+	return ae.encode
+}
+
+func (ae arrayEncoder) encode(e *encodeState, v reflect.Value) error {
+	e.buf = append(e.buf, 'l')
+	n := v.Len()
+	for i := 0; i < n; i++ {
+		if err := ae.elemEnc(e, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, 'e')
+	return nil
+}
+
+type ptrEncoder struct {
+	elemEnc encoderFunc
+}
+
+func newPtrEncoder(t reflect.Type) encoderFunc {
+	pe := ptrEncoder{typeEncoder(t.Elem())}
+	return pe.encode
+}
+
+func (pe ptrEncoder) encode(e *encodeState, v reflect.Value) (err error) {
+	if v.IsNil() {
+		return errors.New("Can't write nil pointer")
+	}
+	if e.detectCycles {
+		var seen ptrKey
+		if seen, err = e.enter(v); err != nil {
+			return
+		}
+		defer delete(e.ptrSeen, seen)
+	}
+	return pe.elemEnc(e, v.Elem())
+}
@@ -0,0 +1,131 @@
+package bencode
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+type recursiveNode struct {
+	Name     string                    `bencode:"name"`
+	Children []recursiveNode           `bencode:"children,omitempty"`
+	Parent   *recursiveNode            `bencode:"parent"`
+	ByName   map[string]*recursiveNode `bencode:"by name,omitempty"`
+}
+
+func TestMarshalRecursiveTypeConcurrently(t *testing.T) {
+	leaf := &recursiveNode{Name: "leaf"}
+	root := recursiveNode{
+		Name:     "root",
+		Children: []recursiveNode{{Name: "a"}, {Name: "b", Children: []recursiveNode{{Name: "c"}}}},
+		ByName:   map[string]*recursiveNode{"z": leaf, "y": {Name: "y"}},
+	}
+	const want = "d7:by named1:yd4:name1:ye1:zd4:name4:leafee8:childrenld4:name1:aed8:childrenld4:name1:cee4:name1:bee4:name4:roote"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var buf bytes.Buffer
+				if err := Marshal(&buf, root); err != nil {
+					t.Error(err)
+					return
+				}
+				if buf.String() != want {
+					t.Errorf("Marshal = %q, want %q", buf.String(), want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMarshalStructAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not reliable with the race detector")
+	}
+	v := structNested{"aa", "q", "ping", unmarshalInnerDict}
+	var buf bytes.Buffer
+	Marshal(&buf, v)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Reset()
+		Marshal(&buf, v)
+	})
+	// Only the values copied out of the inner map for sorting, and
+	// boxing v in an interface, remain.
+	if allocs > 4 {
+		t.Errorf("Marshal made %v allocations, want at most 4", allocs)
+	}
+}
+
+// krpcReply is a typical DHT response, made only of types that
+// AppendMarshal can encode without allocating.
+type krpcReply struct {
+	T string `bencode:"t"`
+	Y string `bencode:"y"`
+	R struct {
+		ID    []byte   `bencode:"id"`
+		Nodes []byte   `bencode:"nodes,omitempty"`
+		Token string   `bencode:"token,omitempty"`
+		Port  int      `bencode:"port"`
+		Hash  [20]byte `bencode:"info_hash"`
+	} `bencode:"r"`
+}
+
+func TestAppendMarshal(t *testing.T) {
+	var reply krpcReply
+	reply.T, reply.Y = "aa", "r"
+	reply.R.ID = []byte("abcdefghij0123456789")
+	reply.R.Token = "tok"
+	reply.R.Port = 6881
+	const want = "d1:rd2:id20:abcdefghij01234567899:info_hash20:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x004:porti6881e5:token3:toke1:t2:aa1:y1:re"
+
+	pkt, err := AppendMarshal([]byte("prefix"), &reply)
+	if err != nil || string(pkt) != "prefix"+want {
+		t.Errorf("AppendMarshal = %q, %v", pkt, err)
+	}
+	b, err := MarshalBytes(reply)
+	if err != nil || string(b) != want {
+		t.Errorf("MarshalBytes = %q, %v", b, err)
+	}
+
+	pkt = make([]byte, 0, 1500)
+	allocs := testing.AllocsPerRun(100, func() {
+		pkt, err = AppendMarshal(pkt[:0], &reply)
+	})
+	if (allocs != 0 && !raceEnabled) || err != nil || string(pkt) != want {
+		t.Errorf("AppendMarshal made %v allocations, err %v", allocs, err)
+	}
+
+	// A failed encoding leaves dst as it was.
+	dst := []byte("keep")
+	if out, err := AppendMarshal(dst, []interface{}{1, make(chan int)}); err == nil || string(out) != "keep" {
+		t.Errorf("AppendMarshal of a channel = %q, %v", out, err)
+	}
+	if out, err := MarshalBytes(nil); err == nil || out != nil {
+		t.Errorf("MarshalBytes(nil) = %q, %v", out, err)
+	}
+
+	// The pooled encodeState does not keep the caller's buffer.
+	for i := 0; i < 10; i++ {
+		buf := make([]byte, 0, 64)
+		AppendMarshal(buf, "x")
+		if b, _ := MarshalBytes("yy"); string(b) != "2:yy" || string(buf[:3]) != "1:x" {
+			t.Fatalf("MarshalBytes = %q after AppendMarshal into %q", b, buf[:3])
+		}
+	}
+}
+
+func TestMarshalDoesNotPoolHugeBuffers(t *testing.T) {
+	e := newEncodeState()
+	e.buf = make([]byte, 0, maxPooledBuffer+1)
+	freeEncodeState(e)
+	for i := 0; i < 10; i++ {
+		if e := newEncodeState(); cap(e.buf) > maxPooledBuffer {
+			t.Fatalf("pooled encodeState has a buffer of %d bytes", cap(e.buf))
+		}
+	}
+}
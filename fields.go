@@ -0,0 +1,277 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jackpal/bencode-go/internal/tags"
+)
+
+// A field describes a struct field that is encoded as a dictionary entry,
+// possibly promoted from an embedded struct.
+type field struct {
+	key       string // the dictionary key
+	name      string // the Go field name, also matched by Unmarshal
+	tagged    bool   // key comes from a struct tag
+	omitEmpty bool
+	index     []int // path through embedded structs, for FieldByIndex
+	typ       reflect.Type
+}
+
+// typeFields returns the fields of struct type t that take part in
+// encoding, following the rules of encoding/json:
+//
+// Unexported fields are ignored, as are fields tagged "-". The fields of an
+// embedded struct, or of a pointer to an embedded struct, are promoted into
+// the parent unless the embedded field has a tag giving it a key.
+//
+// If several fields share a key, the one nested least deeply wins. Among
+// fields at the same depth a tagged field beats an untagged one, and if
+// that still leaves more than one, all of them are dropped.
+//
+// The fields are returned in the order of their index paths.
+func typeFields(t reflect.Type) []field {
+	// Embedded structs still to explore at the current and next depth.
+	var current []field
+	next := []field{{typ: t}}
+
+	// Count of the types queued at the current and next depth, to detect
+	// the same type embedded twice at one depth.
+	var count, nextCount map[reflect.Type]int
+
+	visited := map[reflect.Type]bool{}
+
+	var fields []field
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+
+			for i := 0; i < f.typ.NumField(); i++ {
+				sf := f.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						// Ignore embedded fields of unexported
+						// non-struct types.
+						continue
+					}
+					// Embedded fields of unexported struct types
+					// are walked for their exported fields.
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				key, omitEmpty := tags.Key(sf.Name, sf.Tag)
+				if key == "-" {
+					continue
+				}
+				tagged := tags.IsTagged(sf.Tag)
+
+				index := make([]int, len(f.index)+1)
+				copy(index, f.index)
+				index[len(f.index)] = i
+
+				if sf.Anonymous && !tagged && ft.Kind() == reflect.Struct {
+					// Promote the fields of the embedded struct.
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, field{name: ft.Name(), index: index, typ: ft})
+					}
+					continue
+				}
+
+				fields = append(fields, field{
+					key:       key,
+					name:      sf.Name,
+					tagged:    tagged,
+					omitEmpty: omitEmpty,
+					index:     index,
+					typ:       sf.Type,
+				})
+				if count[f.typ] > 1 {
+					// The enclosing struct was embedded more than
+					// once at this depth, so its fields are
+					// ambiguous. Record the field twice so that it
+					// is dropped below.
+					fields = append(fields, fields[len(fields)-1])
+				}
+			}
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		x := fields
+		if x[i].key != x[j].key {
+			return x[i].key < x[j].key
+		}
+		if len(x[i].index) != len(x[j].index) {
+			return len(x[i].index) < len(x[j].index)
+		}
+		if x[i].tagged != x[j].tagged {
+			return x[i].tagged
+		}
+		return indexLess(x[i].index, x[j].index)
+	})
+
+	// Keep the dominant field for each key.
+	out := fields[:0]
+	for advance, i := 0, 0; i < len(fields); i += advance {
+		fi := fields[i]
+		for advance = 1; i+advance < len(fields); advance++ {
+			if fields[i+advance].key != fi.key {
+				break
+			}
+		}
+		if advance == 1 {
+			out = append(out, fi)
+			continue
+		}
+		if dominant, ok := dominantField(fields[i : i+advance]); ok {
+			out = append(out, dominant)
+		}
+	}
+
+	fields = out
+	sort.Slice(fields, func(i, j int) bool {
+		return indexLess(fields[i].index, fields[j].index)
+	})
+	return fields
+}
+
+// structFields is the lookup table Unmarshal uses to find the field for a
+// dictionary key. It is built once per struct type.
+type structFields struct {
+	list   []field
+	exact  map[string]int // key to index in list
+	folded map[string]int // case-folded key or Go field name to index in list
+}
+
+var fieldCache sync.Map // map[reflect.Type]*structFields
+
+// cachedTypeFields is like typeFields but returns the fields together with
+// their lookup maps, computing them only once per type.
+func cachedTypeFields(t reflect.Type) *structFields {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(*structFields)
+	}
+	list := typeFields(t)
+	sf := &structFields{
+		list:   list,
+		exact:  make(map[string]int, len(list)),
+		folded: make(map[string]int, 2*len(list)),
+	}
+	for i, f := range list {
+		sf.exact[f.key] = i
+	}
+	// The first field in index order wins a case-insensitive match, be it
+	// on its key or on its Go name.
+	for i, f := range list {
+		for _, s := range [2]string{f.key, f.name} {
+			k := string(foldKey(nil, []byte(s)))
+			if _, ok := sf.folded[k]; !ok {
+				sf.folded[k] = i
+			}
+		}
+	}
+	f, _ := fieldCache.LoadOrStore(t, sf)
+	return f.(*structFields)
+}
+
+// lookup returns the field for a dictionary key: the field with exactly
+// that key or, failing that, the first field whose key or name matches it
+// ignoring case. It returns nil if there is none. The scratch buffer is
+// used to fold the key without allocating.
+func (sf *structFields) lookup(key []byte, scratch *[]byte) *field {
+	if i, ok := sf.exact[string(key)]; ok {
+		return &sf.list[i]
+	}
+	*scratch = foldKey((*scratch)[:0], key)
+	if i, ok := sf.folded[string(*scratch)]; ok {
+		return &sf.list[i]
+	}
+	return nil
+}
+
+// foldKey appends the lower case form of key to dst.
+func foldKey(dst, key []byte) []byte {
+	for _, c := range key {
+		if c >= utf8.RuneSelf {
+			return append(dst, bytes.ToLower(key)...)
+		}
+	}
+	for _, c := range key {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// dominantField picks the field that wins among fields sharing a key,
+// which are sorted by depth and then tagged first.
+func dominantField(fields []field) (field, bool) {
+	if len(fields) > 1 && len(fields[0].index) == len(fields[1].index) && fields[0].tagged == fields[1].tagged {
+		return field{}, false
+	}
+	return fields[0], true
+}
+
+func indexLess(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// fieldByIndex returns the field of struct v at the index path, following
+// embedded pointers. It reports false if a nil embedded pointer is met.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for k, i := range index {
+		if k > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc is like fieldByIndex, but allocates nil embedded
+// pointers. It returns an error if a pointer cannot be allocated because
+// it is an unexported field.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for k, i := range index {
+		if k > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("bencode: cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, nil
+}
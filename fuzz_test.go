@@ -0,0 +1,165 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fuzzTorrent covers the field types a torrent client decodes, along with
+// some that no bencode value fits.
+type fuzzTorrent struct {
+	Announce     string                 "announce"
+	AnnounceList [][]string             "announce-list"
+	CreationDate int64                  "creation date"
+	Private      bool                   "private"
+	Comment      []byte                 "comment"
+	Info         *fuzzInfo              "info"
+	URLList      interface{}            "url-list"
+	Extra        map[string]interface{} "extra"
+	Raw          RawMessage             "raw"
+	Big          *big.Int               "big"
+	Num          Number                 "num"
+	Small        int8                   "small"
+	Unsigned     uint16                 "unsigned"
+	Ratio        float32                "ratio"
+	Hashes       [][20]byte             "hashes"
+	Key          [4]fuzzByte            "key"
+	Octets       []fuzzByte             "octets"
+	Stringer     fmt.Stringer           "stringer"
+	IntKeys      map[int]string         "int keys"
+	Chan         chan int               "chan"
+	Func         func()                 "func"
+	Array        [2]interface{}         "array"
+	Ptr          **int                  "ptr"
+	embeddedPtr
+}
+
+// fuzzByte is a type defined from byte, whose slices and arrays decode
+// from strings.
+type fuzzByte byte
+
+type fuzzInfo struct {
+	Name        string "name"
+	PieceLength int    "piece length"
+	Pieces      []byte "pieces"
+	Length      int64  "length"
+	Files       []struct {
+		Length int64    "length"
+		Path   []string "path"
+	} "files"
+}
+
+// FuzzDecode checks that no input makes the decoders panic, and that
+// whatever decodes survives a round trip through Marshal. The regression
+// corpus in testdata/fuzz/FuzzDecode is run by go test.
+func FuzzDecode(f *testing.F) {
+	for _, s := range []string{
+		"i42e", "i-0e", "i1.5e", "4:spam", "le", "de",
+		"d8:announce3:url4:infod6:lengthi1e4:name1:a12:piece lengthi2e6:pieces0:ee",
+		"d4:infod5:filesld6:lengthi1e4:pathl1:aeeeee",
+		"d5:extrad1:ali1ei2eee3:rawd1:ai1ee3:bigi123456789012345678901234567890ee",
+		"d8:stringeri1e4:chani1e4:funcle8:int keysd1:a1:bee",
+	} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v, err := DecodeBytes(data)
+		if err == nil {
+			var buf bytes.Buffer
+			if err := Marshal(&buf, v); err != nil {
+				t.Fatalf("Marshal(%#v) failed: %v", v, err)
+			}
+			v2, err := DecodeBytes(buf.Bytes())
+			if err != nil {
+				t.Fatalf("DecodeBytes(%q) of marshalled value failed: %v", buf.Bytes(), err)
+			}
+			if !reflect.DeepEqual(v, v2) {
+				t.Fatalf("round trip of %q gave %#v, want %#v", data, v2, v)
+			}
+			if IsCanonical(data) && !bytes.Equal(buf.Bytes(), data) {
+				t.Fatalf("canonical input %q marshalled as %q", data, buf.Bytes())
+			}
+			if v3, err := Decode(bytes.NewReader(data)); err != nil || !reflect.DeepEqual(v, v3) {
+				t.Fatalf("Decode(%q) = %#v, %v, want %#v", data, v3, err, v)
+			}
+		}
+
+		vd := NewBytesDecoder(data)
+		if v, err := vd.DecodeValue(); err == nil {
+			if enc, err := v.Encode(); err != nil || !bytes.Equal(enc, data[:vd.InputOffset()]) {
+				t.Fatalf("DecodeValue(%q).Encode() = %q, %v", data, enc, err)
+			}
+		}
+
+		targets := []interface{}{new(Value), new(fuzzTorrent), new(interface{}), new(map[string]int), new([]fuzzInfo), new(RawMessage)}
+		for _, target := range targets {
+			Unmarshal(bytes.NewReader(data), target)
+			UnmarshalBytes(data, target)
+
+			d := NewBytesDecoder(data)
+			d.SetStrict(true)
+			d.SetAliasInput(true)
+			d.DisallowUnknownFields()
+			d.SetLimits(Limits{MaxBytes: 64, MaxStringLen: 16, MaxDepth: 4, MaxEntries: 4})
+			for d.More() {
+				if d.DecodeInto(target) != nil {
+					break
+				}
+			}
+		}
+		td := NewDecoder(bytes.NewReader(data))
+		for i := 0; ; i++ {
+			var err error
+			switch i % 4 {
+			case 1:
+				err = td.Skip()
+			case 3:
+				_, err = td.Decode()
+			default:
+				_, err = td.Token()
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		d := NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		d.SetByteStrings(true)
+		for d.More() {
+			if _, err := d.Decode(); err != nil {
+				break
+			}
+		}
+	})
+}
+
+func TestUnmarshalInvalidTarget(t *testing.T) {
+	var nilTorrent *fuzzTorrent
+	for _, val := range []interface{}{nil, fuzzTorrent{}, nilTorrent} {
+		if err := Unmarshal(strings.NewReader("de"), val); err == nil {
+			t.Errorf("Unmarshal into %#v succeeded", val)
+		}
+		if err := UnmarshalBytes([]byte("de"), val); err == nil {
+			t.Errorf("UnmarshalBytes into %#v succeeded", val)
+		}
+		if err := NewDecoder(strings.NewReader("de")).DecodeInto(val); err == nil {
+			t.Errorf("DecodeInto %#v succeeded", val)
+		}
+	}
+}
+
+func TestScannerDepth(t *testing.T) {
+	deep := strings.Repeat("l", maxNesting+1) + strings.Repeat("e", maxNesting+1)
+	if IsCanonical([]byte(deep)) {
+		t.Errorf("IsCanonical accepted %d nested lists", maxNesting+1)
+	}
+	var buf bytes.Buffer
+	if err := Marshal(&buf, RawMessage(deep)); err == nil {
+		t.Errorf("Marshal accepted a RawMessage of %d nested lists", maxNesting+1)
+	}
+}
@@ -0,0 +1,118 @@
+package bencode
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalAs reads a single bencode value from r and returns it
+// unmarshalled into a new value of type T, as by Unmarshal. If some part
+// of the input does not fit T, it returns the value decoded so far along
+// with the UnmarshalTypeError.
+//
+//	t, err := bencode.UnmarshalAs[Torrent](r)
+func UnmarshalAs[T any](r io.Reader) (T, error) {
+	var v T
+	err := Unmarshal(r, &v)
+	return v, err
+}
+
+// DecodeList reads a single bencode list from r and returns its items
+// unmarshalled into values of type T. Unlike Decode, it needs no type
+// assertions on the result:
+//
+//	peers, err := bencode.DecodeList[string](r)
+//
+// The list is never nil unless the input is not a list, in which case an
+// UnmarshalTypeError is returned.
+func DecodeList[T any](r io.Reader) ([]T, error) {
+	return UnmarshalAs[[]T](r)
+}
+
+// Get returns the value found at path in v, unmarshalled into a value of
+// type T as by Unmarshal. The path is written like the Field of an
+// UnmarshalTypeError: dictionary keys separated by dots, and list indexes
+// in brackets, so that
+//
+//	n, err := bencode.Get[int64](meta, "info.files[3].length")
+//
+// reads the length of the fourth file of a torrent. An empty path stands
+// for v itself. Keys holding '.' or '[' cannot be reached with Get; use
+// Value.Get for those.
+//
+// Get returns an error if there is no value at path. If the value does not
+// fit T, the UnmarshalTypeError has the full path as its Field, and an
+// Offset counted from the start of the value found.
+func Get[T any](v Value, path string) (T, error) {
+	var x T
+	found, err := lookup(v, path)
+	if err != nil {
+		return x, err
+	}
+	if p, ok := interface{}(&x).(*Value); ok {
+		*p = found
+		return x, nil
+	}
+	data, err := found.Encode()
+	if err != nil {
+		return x, err
+	}
+	err = UnmarshalBytes(data, &x)
+	if ute, ok := err.(*UnmarshalTypeError); ok && path != "" {
+		switch {
+		case ute.Field == "":
+			ute.Field = path
+		case ute.Field[0] == '[':
+			ute.Field = path + ute.Field
+		default:
+			ute.Field = path + "." + ute.Field
+		}
+	}
+	return x, err
+}
+
+// lookup follows path, in the form described for Get, from v.
+func lookup(v Value, path string) (Value, error) {
+	for i := 0; i < len(path); {
+		var next Value
+		switch path[i] {
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return Value{}, fmt.Errorf("bencode: invalid path %q", path)
+			}
+			n, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || n < 0 {
+				return Value{}, fmt.Errorf("bencode: invalid path %q", path)
+			}
+			next = v.Index(n)
+			i += end + 1
+		case '.':
+			if i == 0 || i+1 == len(path) || path[i+1] == '.' || path[i+1] == '[' {
+				return Value{}, fmt.Errorf("bencode: invalid path %q", path)
+			}
+			i++
+			continue
+		default:
+			if i > 0 && path[i-1] != '.' {
+				return Value{}, fmt.Errorf("bencode: invalid path %q", path)
+			}
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			next = v.Get(path[i : i+end])
+			i += end
+		}
+		if next.Kind() == KindInvalid {
+			return Value{}, fmt.Errorf("bencode: no value at %q", path[:i])
+		}
+		v = next
+	}
+	if v.Kind() == KindInvalid {
+		return Value{}, fmt.Errorf("bencode: no value at %q", path)
+	}
+	return v, nil
+}
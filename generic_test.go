@@ -0,0 +1,119 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const genericTorrent = "d8:announce3:url4:infod5:filesld6:lengthi1e4:pathl1:aeed6:lengthi2e4:pathl1:b1:ceee4:name4:demoee"
+
+func TestUnmarshalAs(t *testing.T) {
+	type file struct {
+		Length int64
+		Path   []string
+	}
+	type info struct {
+		Name  string
+		Files []file
+	}
+	type torrent struct {
+		Announce string
+		Info     info
+	}
+	got, err := UnmarshalAs[torrent](strings.NewReader(genericTorrent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := torrent{"url", info{"demo", []file{{1, []string{"a"}}, {2, []string{"b", "c"}}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalAs = %+v, want %+v", got, want)
+	}
+
+	n, err := UnmarshalAs[int](strings.NewReader("4:spam"))
+	var ute *UnmarshalTypeError
+	if n != 0 || !errors.As(err, &ute) || ute.Value != "string" {
+		t.Errorf("UnmarshalAs[int] of a string = %v, %v", n, err)
+	}
+	if _, err := UnmarshalAs[int](strings.NewReader("i1")); err == nil {
+		t.Error("UnmarshalAs of truncated input succeeded")
+	}
+}
+
+func TestDecodeList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		err  string
+	}{
+		{"le", []string{}, ""},
+		{"l4:spam3:egge", []string{"spam", "egg"}, ""},
+		{"l4:spami3ee", []string{"spam", ""}, "bencode: cannot unmarshal integer at [1] into Go value of type string"},
+		{"d1:a1:be", nil, "bencode: cannot unmarshal dictionary into Go value of type []string"},
+	}
+	for _, tt := range tests {
+		got, err := DecodeList[string](strings.NewReader(tt.in))
+		if !reflect.DeepEqual(got, tt.want) || tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("DecodeList(%q) = %#v, %v, want %#v, %s", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	v, err := DecodeValue(strings.NewReader(genericTorrent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Get[int64](v, "info.files[1].length"); n != 2 || err != nil {
+		t.Errorf("Get[int64] = %v, %v", n, err)
+	}
+	if s, err := Get[string](v, "info.files[1].path[0]"); s != "b" || err != nil {
+		t.Errorf("Get[string] = %q, %v", s, err)
+	}
+	if p, err := Get[[]string](v, "info.files[1].path"); !reflect.DeepEqual(p, []string{"b", "c"}) || err != nil {
+		t.Errorf("Get[[]string] = %q, %v", p, err)
+	}
+	if m, err := Get[map[string]interface{}](v, "info.files[0]"); m["length"] != int64(1) || err != nil {
+		t.Errorf("Get[map[string]interface{}] = %v, %v", m, err)
+	}
+	if x, err := Get[Value](v, "info"); x.Kind() != KindDict || x.Get("name").String() != "demo" || err != nil {
+		t.Errorf("Get[Value] = %v, %v", x, err)
+	}
+	if x, err := Get[Value](v, ""); x.Len() != 2 || err != nil {
+		t.Errorf("Get with an empty path = %v, %v", x, err)
+	}
+	list := ListValue(ListValue(IntValue(7)))
+	if n, err := Get[int](list, "[0][0]"); n != 7 || err != nil {
+		t.Errorf("Get of a nested list = %v, %v", n, err)
+	}
+
+	errs := []struct {
+		path string
+		err  string
+	}{
+		{"info.files[2].length", `bencode: no value at "info.files[2]"`},
+		{"info.name.first", `bencode: no value at "info.name.first"`},
+		{"announce[0]", `bencode: no value at "announce[0]"`},
+		{"info..name", `bencode: invalid path "info..name"`},
+		{"info.", `bencode: invalid path "info."`},
+		{".info", `bencode: invalid path ".info"`},
+		{"info.files[x]", `bencode: invalid path "info.files[x]"`},
+		{"info.files[-1]", `bencode: invalid path "info.files[-1]"`},
+		{"info.files[0", `bencode: invalid path "info.files[0"`},
+		{"info.files[0]path", `bencode: invalid path "info.files[0]path"`},
+		{"info.files", "bencode: cannot unmarshal list at info.files into Go value of type int"},
+		{"info.files[0].path", "bencode: cannot unmarshal list at info.files[0].path into Go value of type int"},
+	}
+	for _, tt := range errs {
+		if _, err := Get[int](v, tt.path); err == nil || err.Error() != tt.err {
+			t.Errorf("Get(%q) = %v, want %s", tt.path, err, tt.err)
+		}
+	}
+	if _, err := Get[[]int](v, "info.files[1].path"); err == nil || err.Error() != "bencode: cannot unmarshal string at info.files[1].path[0] into Go value of type int" {
+		t.Errorf("Get[[]int] = %v", err)
+	}
+	if _, err := Get[int](Value{}, ""); err == nil {
+		t.Error("Get of the zero Value succeeded")
+	}
+}
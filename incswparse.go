@@ -1,10 +1,7 @@
 package bencode
 
 import (
-    "bufio"
     "bytes"
-    "errors"
-    "strconv"
 )
 
 // A relatively fast unmarshaler.
@@ -12,11 +9,18 @@ import (
 // License: https://github.com/IncSW/go-bencode/blob/master/LICENSE
 
 // Differences from IncSW are for compatibility with the existing bencode-go API:
-// (a) Uses a bufio.Reader rather than a raw []byte
-// (b) Strings are returned as golang strings rather than as raw []byte arrays.
-
-func decodeFromReader(r *bufio.Reader) (data interface{}, err error) {
-    result, err := unmarshal(r)
+// (a) Reads through a decodeState, from either a bufio.Reader or a []byte
+// (b) Strings are returned as golang strings rather than as raw []byte arrays,
+//     unless the byteStrings option is set.
+
+// decode returns the generic representation of the next value. It returns
+// io.EOF if there is no input left, and io.ErrUnexpectedEOF if the input
+// ends inside the value.
+func (d *decodeState) decode() (interface{}, error) {
+    if err := d.begin(); err != nil {
+        return nil, err
+    }
+    result, err := d.any()
     if err != nil {
         return nil, err
     }
@@ -24,39 +28,39 @@ func decodeFromReader(r *bufio.Reader) (data interface{}, err error) {
     return result, nil
 }
 
-func unmarshal(data *bufio.Reader) (interface{}, error) {
-    ch, err := data.ReadByte()
+func (d *decodeState) any() (interface{}, error) {
+    ch, err := d.readByte()
     if err != nil {
         return nil, err
     }
     switch ch {
     case 'i':
-        integerBuffer, err := optimisticReadBytes(data, 'e')
-        if err != nil {
-            return nil, err
-        }
-        integerBuffer = integerBuffer[:len(integerBuffer)-1]
-
-        integer, err := strconv.ParseInt(string(integerBuffer), 10, 64)
+        integerBuffer, err := d.readInteger()
         if err != nil {
             return nil, err
         }
 
-        return integer, nil
+        return d.integerValue(integerBuffer)
 
     case 'l':
+        if err := d.enter(); err != nil {
+            return nil, err
+        }
+        defer d.leave()
         list := []interface{}{}
         for {
-            c, err2 := data.ReadByte()
-            if err2 == nil {
-                if c == 'e' {
-                    return list, nil
-                } else {
-                    data.UnreadByte()
-                }
+            end, err := d.atEnd()
+            if err != nil {
+                return nil, err
+            }
+            if end {
+                return list, nil
             }
 
-            value, err := unmarshal(data)
+            if err := d.checkEntries(len(list) + 1); err != nil {
+                return nil, err
+            }
+            value, err := d.any()
             if err != nil {
                 return nil, err
             }
@@ -65,27 +69,38 @@ func unmarshal(data *bufio.Reader) (interface{}, error) {
         }
 
     case 'd':
+        if err := d.enter(); err != nil {
+            return nil, err
+        }
+        defer d.leave()
         dictionary := map[string]interface{}{}
-        for {
-            c, err2 := data.ReadByte()
-            if err2 == nil {
-                if c == 'e' {
-                    return dictionary, nil
-                } else {
-                    data.UnreadByte()
-                }
+        var prevKey []byte
+        for n := 1; ; n++ {
+            end, err := d.atEnd()
+            if err != nil {
+                return nil, err
+            }
+            if end {
+                return dictionary, nil
+            }
+
+            if err := d.checkEntries(n); err != nil {
+                return nil, err
             }
-            value, err := unmarshal(data)
+            keyBuffer, err := d.readKey()
             if err != nil {
                 return nil, err
             }
 
-            key, ok := value.(string)
-            if !ok {
-                return nil, errors.New("bencode: non-string dictionary key")
+            if d.opts.strict {
+                if err := d.checkKeyOrder(n == 1, prevKey, keyBuffer); err != nil {
+                    return nil, err
+                }
+                prevKey = append(prevKey[:0], keyBuffer...)
             }
+            key := string(keyBuffer)
 
-            value, err = unmarshal(data)
+            value, err := d.any()
             if err != nil {
                 return nil, err
             }
@@ -94,38 +109,21 @@ func unmarshal(data *bufio.Reader) (interface{}, error) {
         }
 
     default:
-        data.UnreadByte()
-        stringLengthBuffer, err := optimisticReadBytes(data, ':')
-        if err != nil {
-            return nil, err
+        if ch < '0' || ch > '9' {
+            return nil, d.unexpected(ch, expectedValue)
         }
-        stringLengthBuffer = stringLengthBuffer[:len(stringLengthBuffer)-1]
-
-        stringLength, err := strconv.ParseInt(string(stringLengthBuffer), 10, 64)
+        d.unreadByte()
+        buf, err := d.readString()
         if err != nil {
             return nil, err
         }
 
-        buf := make([]byte, stringLength)
-
-        _, err = readAtLeast(data, buf, int(stringLength))
-
-        return string(buf), err
-    }
-}
-
-// Reads bytes out of local buffer if possible, which avoids an extra copy.
-// The result []byte is only guarenteed to be valid until the next call to a Read method.
-func optimisticReadBytes(data *bufio.Reader, delim byte) ([]byte, error) {
-    buffered := data.Buffered()
-    var buffer []byte
-    var err error
-    if buffer, err = data.Peek(buffered); err != nil {
-        return nil, err
-    }
-
-    if i := bytes.IndexByte(buffer, delim); i >= 0 {
-        return data.ReadSlice(delim)
+        if d.opts.byteStrings {
+            if d.alias() {
+                return buf, nil
+            }
+            return bytes.Clone(buf), nil
+        }
+        return string(buf), nil
     }
-    return data.ReadBytes(delim)
 }
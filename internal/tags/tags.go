@@ -0,0 +1,80 @@
+// Package tags interprets the struct tags that name the dictionary keys of
+// struct fields. It is shared by package bencode and by bencodegen, so
+// that generated code uses the same keys as Marshal and Unmarshal.
+package tags
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Key returns the dictionary key of the struct field with the given name
+// and tag, and whether the field has the omitempty option. A key of "-"
+// means that the field is ignored.
+func Key(name string, tag reflect.StructTag) (key string, omitEmpty bool) {
+	key = name
+	if len(tag) > 0 {
+		// Backwards compatability
+		// If there's a bencode key/value entry in the tag, use it.
+		var tagOpt Options
+		key, tagOpt = Parse(tag.Get("bencode"))
+		if len(key) == 0 {
+			key = tag.Get("bencode")
+			if len(key) == 0 && !strings.Contains(string(tag), ":") {
+				// If there is no ":" in the tag, assume it is an old-style tag.
+				key = string(tag)
+			} else {
+				key = name
+			}
+		}
+		omitEmpty = tagOpt.Contains("omitempty")
+	}
+	return
+}
+
+// IsTagged reports whether tag names a key, in either the bencode:"key"
+// or the original single-string syntax.
+func IsTagged(tag reflect.StructTag) bool {
+	if len(tag) == 0 {
+		return false
+	}
+	if name, _ := Parse(tag.Get("bencode")); name != "" {
+		return true
+	}
+	return tag.Get("bencode") == "" && !strings.Contains(string(tag), ":")
+}
+
+// Options is the string following a comma in a struct field's "bencode"
+// tag, or the empty string. It does not include the leading comma.
+type Options string
+
+// Parse splits a struct field's bencode tag into its name and
+// comma-separated options.
+func Parse(tag string) (string, Options) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], Options(tag[idx+1:])
+	}
+	return tag, Options("")
+}
+
+// Contains reports whether a comma-separated list of options
+// contains a particular substr flag. substr must be surrounded by a
+// string boundary or commas.
+func (o Options) Contains(optionName string) bool {
+	if len(o) == 0 {
+		return false
+	}
+	s := string(o)
+	for s != "" {
+		var next string
+		i := strings.Index(s, ",")
+		if i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == optionName {
+			return true
+		}
+		s = next
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package bencode
+
+import "fmt"
+
+// Limits bounds the resources spent decoding a single value, so that
+// hostile input cannot exhaust memory or stack. A zero field means no
+// limit, except that nesting is never allowed to go deeper than 10000
+// levels, which would risk overflowing the stack.
+type Limits struct {
+	// MaxBytes is the largest encoded size of a value.
+	MaxBytes int64
+
+	// MaxStringLen is the longest string, including dictionary keys.
+	// Without it a string is still only allocated as its bytes arrive,
+	// so a length prefix alone cannot force a large allocation.
+	MaxStringLen int
+
+	// MaxDepth is the deepest nesting of lists and dictionaries.
+	MaxDepth int
+
+	// MaxEntries is the largest number of elements in a list, or of
+	// key-value pairs in a dictionary.
+	MaxEntries int
+}
+
+// DefaultLimits are the limits used by Decode, DecodeBytes, Unmarshal and
+// UnmarshalBytes, and the initial limits of a new Decoder. They only
+// bound the nesting depth. DefaultLimits must not be changed while values
+// are being decoded; to apply other limits to a single value, use the
+// methods of Limits, such as Limits.Unmarshal.
+var DefaultLimits = Limits{
+	MaxDepth: 1000,
+}
+
+// maxNesting bounds the nesting of lists and dictionaries whatever the
+// Limits, since the decoders and the scanner recurse into each level.
+const maxNesting = 10000
+
+// A LimitError is returned when the input exceeds one of the Limits.
+type LimitError struct {
+	Limit string // the name of the Limits field, such as "MaxDepth"
+	Max   int64  // the value of the limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("bencode: input exceeds %s of %d", e.Limit, e.Max)
+}
+
+// enter records the start of a list or dictionary.
+func (d *decodeState) enter() error {
+	d.depth++
+	max := d.opts.limits.MaxDepth
+	if max <= 0 || max > maxNesting {
+		max = maxNesting
+	}
+	if d.depth > max {
+		return &LimitError{"MaxDepth", int64(max)}
+	}
+	return d.checkSize(0)
+}
+
+// leave records the end of a list or dictionary.
+func (d *decodeState) leave() {
+	d.depth--
+}
+
+// checkEntries returns an error if a list or dictionary has more than n
+// entries.
+func (d *decodeState) checkEntries(n int) error {
+	if max := d.opts.limits.MaxEntries; max > 0 && n > max {
+		return &LimitError{"MaxEntries", int64(max)}
+	}
+	return nil
+}
+
+// checkSize returns an error if the current value would exceed MaxBytes
+// after another n bytes of input.
+func (d *decodeState) checkSize(n int) error {
+	if max := d.opts.limits.MaxBytes; max > 0 && d.pos()-d.start+int64(n) > max {
+		return &LimitError{"MaxBytes", max}
+	}
+	return nil
+}
+
+// checkStringLen returns an error if a string of the given length is too
+// long.
+func (d *decodeState) checkStringLen(length int) error {
+	if max := d.opts.limits.MaxStringLen; max > 0 && length > max {
+		return &LimitError{"MaxStringLen", int64(max)}
+	}
+	return d.checkSize(length)
+}
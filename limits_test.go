@@ -0,0 +1,131 @@
+package bencode
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func nested(depth int) string {
+	return strings.Repeat("l", depth) + strings.Repeat("e", depth)
+}
+
+func checkLimitError(t *testing.T, what string, err error, limit string) {
+	t.Helper()
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != limit {
+		t.Errorf("%s: got %v, want a %s LimitError", what, err, limit)
+	}
+}
+
+func TestDefaultDepthLimit(t *testing.T) {
+	input := nested(DefaultLimits.MaxDepth + 1)
+	_, err := Decode(strings.NewReader(input))
+	checkLimitError(t, "Decode", err, "MaxDepth")
+	_, err = DecodeBytes([]byte(input))
+	checkLimitError(t, "DecodeBytes", err, "MaxDepth")
+	var s struct{ A int }
+	err = Unmarshal(strings.NewReader(input), &s)
+	checkLimitError(t, "Unmarshal", err, "MaxDepth")
+	var raw RawMessage
+	err = UnmarshalBytes([]byte(input), &raw)
+	checkLimitError(t, "UnmarshalBytes", err, "MaxDepth")
+
+	if _, err = DecodeBytes([]byte(nested(DefaultLimits.MaxDepth))); err != nil {
+		t.Errorf("nesting at the limit: %v", err)
+	}
+}
+
+func TestHugeStringLengthDoesNotAllocate(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := Decode(strings.NewReader("9999999999:abc"))
+	runtime.ReadMemStats(&after)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("decoding a short input allocated %d bytes", n)
+	}
+}
+
+func TestDecoderLimits(t *testing.T) {
+	tests := []struct {
+		limits Limits
+		input  string
+		limit  string
+	}{
+		{Limits{MaxStringLen: 3}, "l3:abc4:abcde", "MaxStringLen"},
+		{Limits{MaxStringLen: 3}, "d4:abcdi1ee", "MaxStringLen"},
+		{Limits{MaxEntries: 2}, "li1ei2ei3ee", "MaxEntries"},
+		{Limits{MaxEntries: 2}, "d1:ai1e1:bi2e1:ci3ee", "MaxEntries"},
+		{Limits{MaxBytes: 10}, "l3:abc3:defe", "MaxBytes"},
+		{Limits{MaxBytes: 10}, "i12345678901e", "MaxBytes"},
+		{Limits{MaxBytes: 10}, "llllllllllllee", "MaxBytes"},
+		{Limits{MaxDepth: 2}, "ld1:alleee", "MaxDepth"},
+	}
+	for _, tt := range tests {
+		for _, d := range []*Decoder{NewDecoder(strings.NewReader(tt.input)), NewBytesDecoder([]byte(tt.input))} {
+			d.SetLimits(tt.limits)
+			_, err := d.Decode()
+			checkLimitError(t, tt.input, err, tt.limit)
+		}
+		for _, d := range []*Decoder{NewDecoder(strings.NewReader(tt.input)), NewBytesDecoder([]byte(tt.input))} {
+			d.SetLimits(tt.limits)
+			var v struct {
+				A RawMessage
+				B []string
+			}
+			err := d.DecodeInto(&v)
+			checkLimitError(t, "DecodeInto "+tt.input, err, tt.limit)
+		}
+	}
+}
+
+func TestDecoderMaxBytesPerValue(t *testing.T) {
+	d := NewDecoder(strings.NewReader("3:abc3:def3:ghi"))
+	d.SetLimits(Limits{MaxBytes: 5})
+	for d.More() {
+		if _, err := d.Decode(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNestingAlwaysLimited(t *testing.T) {
+	input := nested(maxNesting + 1)
+	l := Limits{MaxStringLen: 1 << 20, MaxEntries: 1 << 20}
+	for _, d := range []*Decoder{NewDecoder(strings.NewReader(input)), NewBytesDecoder([]byte(input))} {
+		d.SetLimits(l)
+		_, err := d.Decode()
+		checkLimitError(t, "Decode", err, "MaxDepth")
+	}
+	d := NewBytesDecoder([]byte(input))
+	d.SetLimits(l)
+	var v []any
+	checkLimitError(t, "DecodeInto", d.DecodeInto(&v), "MaxDepth")
+
+	if _, err := l.DecodeBytes([]byte(nested(maxNesting))); err != nil {
+		t.Errorf("nesting at the hard limit: %v", err)
+	}
+}
+
+func TestLimitsMethods(t *testing.T) {
+	l := Limits{MaxStringLen: 3}
+	input := "l3:abc4:abcde"
+	_, err := l.Decode(strings.NewReader(input))
+	checkLimitError(t, "Decode", err, "MaxStringLen")
+	_, err = l.DecodeBytes([]byte(input))
+	checkLimitError(t, "DecodeBytes", err, "MaxStringLen")
+	var v []string
+	err = l.Unmarshal(strings.NewReader(input), &v)
+	checkLimitError(t, "Unmarshal", err, "MaxStringLen")
+	err = l.UnmarshalBytes([]byte(input), &v)
+	checkLimitError(t, "UnmarshalBytes", err, "MaxStringLen")
+
+	if err = l.UnmarshalBytes([]byte("l3:abce"), &v); err != nil || len(v) != 1 {
+		t.Errorf("UnmarshalBytes within the limits = %v, %v", v, err)
+	}
+}
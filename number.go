@@ -0,0 +1,73 @@
+package bencode
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// A Number is a bencode integer kept as its decimal text. Bencode integers
+// have no size limit, and a Number holds any of them without loss.
+//
+// Marshal writes a Number as an integer, and Unmarshal stores integers in
+// Number fields. A Decoder returns integers as Numbers after UseNumber.
+type Number string
+
+var (
+	numberType = reflect.TypeOf(Number(""))
+	bigIntType = reflect.TypeOf(big.Int{})
+)
+
+// String returns the text of the number.
+func (n Number) String() string { return string(n) }
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// Uint64 returns the number as a uint64.
+func (n Number) Uint64() (uint64, error) {
+	return strconv.ParseUint(string(n), 10, 64)
+}
+
+// BigInt returns the number as a big.Int.
+func (n Number) BigInt() (*big.Int, error) {
+	x, ok := new(big.Int).SetString(string(n), 10)
+	if !ok {
+		return nil, fmt.Errorf("bencode: invalid Number %q", string(n))
+	}
+	return x, nil
+}
+
+// isInteger reports whether buf is a decimal integer, with an optional
+// minus sign but no fraction or exponent.
+func isInteger(buf []byte) bool {
+	if len(buf) > 0 && buf[0] == '-' {
+		buf = buf[1:]
+	}
+	return isDigits(buf)
+}
+
+// integerValue returns the generic representation of an integer with text
+// buf, just read by readInteger: an int64, or a *big.Int if it does not
+// fit, or a Number if useNumber is set.
+func (d *decodeState) integerValue(buf []byte) (interface{}, error) {
+	if !d.opts.useNumber {
+		if i, ok := parseInteger(buf); ok {
+			return i, nil
+		}
+	}
+	if !isInteger(buf) {
+		return nil, d.tokenError(badInteger(buf), buf)
+	}
+	if d.opts.useNumber {
+		return Number(buf), nil
+	}
+	if i, err := strconv.ParseInt(string(buf), 10, 64); err == nil {
+		return i, nil
+	}
+	x, _ := new(big.Int).SetString(string(buf), 10)
+	return x, nil
+}
@@ -0,0 +1,160 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const hugeInt = "123456789012345678901234567890"
+
+func TestDecodeLargeIntegers(t *testing.T) {
+	for _, text := range []string{hugeInt, "-" + hugeInt, "18446744073709551615", "-9223372036854775809"} {
+		input := "i" + text + "e"
+		want, _ := new(big.Int).SetString(text, 10)
+
+		v1, err := Decode(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2, err := DecodeBytes([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v3 interface{}
+		if err = Unmarshal(strings.NewReader(input), &v3); err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range []interface{}{v1, v2, v3} {
+			if x, ok := v.(*big.Int); !ok || x.Cmp(want) != 0 {
+				t.Errorf("%s decoded as %T %v", input, v, v)
+			}
+		}
+	}
+
+	if v, err := DecodeBytes([]byte("i9223372036854775807e")); err != nil || v != int64(9223372036854775807) {
+		t.Errorf("got %T %v, %v", v, v, err)
+	}
+	if _, err := DecodeBytes([]byte("i7.5e")); err == nil {
+		t.Error("Decode accepted a fractional integer")
+	}
+}
+
+func TestDecoderUseNumber(t *testing.T) {
+	input := "li1ei" + hugeInt + "ee"
+	d := NewDecoder(strings.NewReader(input + input))
+	d.UseNumber()
+	v, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var into interface{}
+	if err = d.DecodeInto(&into); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{v, into} {
+		list := v.([]interface{})
+		if list[0] != Number("1") || list[1] != Number(hugeInt) {
+			t.Errorf("got %#v", list)
+		}
+	}
+}
+
+type numberFields struct {
+	N Number   "n"
+	P *big.Int "p"
+	V big.Int  "v"
+	U uint64   "u"
+}
+
+func TestUnmarshalNumberFields(t *testing.T) {
+	input := "d1:ni" + hugeInt + "e1:pi-" + hugeInt + "e1:ui18446744073709551615e1:vi42ee"
+	var fromReader, fromBytes numberFields
+	if err := Unmarshal(strings.NewReader(input), &fromReader); err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalBytes([]byte(input), &fromBytes); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []numberFields{fromReader, fromBytes} {
+		if f.N != hugeInt || f.P == nil || f.P.String() != "-"+hugeInt ||
+			f.V.Int64() != 42 || f.U != 18446744073709551615 {
+			t.Errorf("got %+v", f)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, fromReader); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Errorf("Marshal = %q, want %q", buf.String(), input)
+	}
+}
+
+func TestUnmarshalFractionalNumberFields(t *testing.T) {
+	// The fields that cannot hold a fraction are reported, and the rest
+	// of the dictionary is still decoded.
+	input := "d1:ni1.5e1:pi2.5e1:ui7e1:vi-3.5ee"
+	for _, unmarshal := range []func(*numberFields) error{
+		func(f *numberFields) error { return Unmarshal(strings.NewReader(input), f) },
+		func(f *numberFields) error { return UnmarshalBytes([]byte(input), f) },
+	} {
+		var f numberFields
+		err := unmarshal(&f)
+		var te *UnmarshalTypeError
+		if !errors.As(err, &te) || te.Value != "integer 1.5" || te.Type != numberType || te.Field != "n" {
+			t.Errorf("got %v, want an UnmarshalTypeError for field n", err)
+		}
+		if f.N != "" || f.P == nil || f.P.Sign() != 0 || f.U != 7 || f.V.Sign() != 0 {
+			t.Errorf("got %+v", f)
+		}
+	}
+}
+
+func TestNumberMethods(t *testing.T) {
+	n := Number("-42")
+	if i, err := n.Int64(); err != nil || i != -42 {
+		t.Errorf("Int64 = %d, %v", i, err)
+	}
+	if _, err := n.Uint64(); err == nil {
+		t.Error("Uint64 accepted a negative number")
+	}
+	if x, err := Number(hugeInt).BigInt(); err != nil || x.String() != hugeInt {
+		t.Errorf("BigInt = %v, %v", x, err)
+	}
+	if _, err := Number("1.5").BigInt(); err == nil {
+		t.Error("BigInt accepted 1.5")
+	}
+}
+
+func TestMarshalNumber(t *testing.T) {
+	tests := []SVPair{
+		{"i0e", Number("")},
+		{"i" + hugeInt + "e", Number(hugeInt)},
+		{"i-5e", big.NewInt(-5)},
+		{"li7ee", []interface{}{*big.NewInt(7)}},
+	}
+	for _, tt := range tests {
+		if err := checkMarshal(tt.s, tt.v); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, n := range []Number{"1.5", "abc", "-", "1e5"} {
+		if err := Marshal(&bytes.Buffer{}, n); err == nil {
+			t.Errorf("Marshal accepted Number %q", n)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(Number("007")); err != nil {
+		t.Fatal(err)
+	}
+	enc.SetCanonical(true)
+	if err := enc.Encode(Number("007")); err == nil {
+		t.Error("canonical Encoder accepted a Number with leading zeros")
+	}
+}
@@ -9,50 +9,11 @@ package bencode
 
 import (
 	"bufio"
-	"errors"
-	"fmt"
 	"io"
-	"strconv"
+	"math"
 	"sync"
 )
 
-// Parser
-//
-// Implements parsing but not the actions.  Those are
-// carried out by the implementation of the builder interface.
-// A builder represents the object being created.
-// Calling a method like Int64(i) sets that object to i.
-// Calling a method like Elem(i) or Key(s) creates a
-// new builder for a subpiece of the object (logically,
-// a slice element or a map key).
-//
-// There are two Builders, in other files.
-// The decoder builds a generic bencode structures
-// in which maps are maps.
-// The structBuilder copies data into a possibly
-// nested data structure, using the "map keys"
-// as struct field names.
-
-// A builder is an interface implemented by clients and passed
-// to the bencode parser.  It gives clients full control over the
-// eventual representation returned by the parser.
-type builder interface {
-	// Set value
-	Int64(i int64)
-	Uint64(i uint64)
-	Float64(f float64)
-	String(s string)
-	Array()
-	Map()
-
-	// Create sub-Builders
-	Elem(i int) builder
-	Key(s string) builder
-
-	// Flush changes to parent builder if necessary.
-	Flush()
-}
-
 // Deprecated: This type is currently unused. It is exposed for backwards
 // compatability. The public API that previously used this type,
 //
@@ -69,53 +30,26 @@ type Reader interface {
 	io.ByteScanner
 }
 
-func decodeInt64(r *bufio.Reader, delim byte) (data int64, err error) {
-	buf, err := readSlice(r, delim)
-	if err != nil {
-		return
-	}
-	data, err = strconv.ParseInt(string(buf), 10, 64)
-	return
-}
+// decodeOptions holds the settings shared by Decode and Unmarshal.
+type decodeOptions struct {
+	// strict rejects input that is not in canonical form.
+	strict bool
 
-// Read bytes up until delim, return slice without delimiter byte.
-func readSlice(r *bufio.Reader, delim byte) (data []byte, err error) {
-	if data, err = r.ReadSlice(delim); err != nil {
-		return
-	}
-	lenData := len(data)
-	if lenData > 0 {
-		data = data[:lenData-1]
-	} else {
-		panic("bad r.ReadSlice() length")
-	}
-	return
-}
+	// byteStrings makes Decode return string values as []byte.
+	byteStrings bool
 
-func decodeString(r *bufio.Reader) (data string, err error) {
-	length, err := decodeInt64(r, ':')
-	if err != nil {
-		return
-	}
-	if length < 0 {
-		err = errors.New("Bad string length")
-		return
-	}
+	// useNumber makes generic values hold integers as Number.
+	useNumber bool
 
-	// Can we peek that much data out of r?
-	if peekBuf, peekErr := r.Peek(int(length)); peekErr == nil {
-		data = string(peekBuf)
-		_, err = r.Discard(int(length))
-		return
-	}
+	// limits bounds the resources spent on each value.
+	limits Limits
 
-	var buf = make([]byte, length)
-	_, err = readFull(r, buf)
-	if err != nil {
-		return
-	}
-	data = string(buf)
-	return
+	// alias lets []byte results share memory with in-memory input.
+	alias bool
+
+	// disallowUnknownFields makes Unmarshal reject dictionary keys that
+	// match no struct field.
+	disallowUnknownFields bool
 }
 
 // Like io.ReadFull, but takes a bufio.Reader.
@@ -141,126 +75,163 @@ func readAtLeast(r *bufio.Reader, buf []byte, min int) (n int, err error) {
 	return
 }
 
-func parseFromReader(r *bufio.Reader, build builder) (err error) {
-	c, err := r.ReadByte()
-	if err != nil {
-		goto exit
-	}
-	switch {
-	case c >= '0' && c <= '9':
-		// String
-		err = r.UnreadByte()
-		if err != nil {
-			goto exit
-		}
-		var str string
-		str, err = decodeString(r)
-		if err != nil {
-			goto exit
-		}
-		build.String(str)
+// A valueReader is a pooled bufio.Reader used by the single-value entry
+// points such as Decode and Unmarshal. Unlike a plain bufio.Reader it never
+// consumes input beyond the end of the value being decoded: if the
+// underlying reader can seek, the unused buffered bytes are given back by
+// seeking backwards on release, otherwise the underlying reader is read
+// through a framingReader.
+type valueReader struct {
+	br      bufio.Reader
+	seeker  io.Seeker
+	framing framingReader
+}
 
-	case c == 'd':
-		// dictionary
+var valueReaderPool sync.Pool
 
-		build.Map()
-		for {
-			c, err = r.ReadByte()
-			if err != nil {
-				goto exit
-			}
-			if c == 'e' {
-				break
-			}
-			err = r.UnreadByte()
-			if err != nil {
-				goto exit
-			}
-			var key string
-			key, err = decodeString(r)
-			if err != nil {
-				goto exit
-			}
-			// TODO: in pendantic mode, check for keys in ascending order.
-			err = parseFromReader(r, build.Key(key))
-			if err != nil {
-				goto exit
-			}
+func newValueReader(r io.Reader) *valueReader {
+	vr, _ := valueReaderPool.Get().(*valueReader)
+	if vr == nil {
+		vr = new(valueReader)
+	}
+	vr.seeker = nil
+	if s, ok := r.(io.Seeker); ok {
+		// Pipes and terminals implement io.Seeker but fail to seek.
+		if _, err := s.Seek(0, io.SeekCurrent); err == nil {
+			vr.seeker = s
 		}
+	}
+	if vr.seeker != nil {
+		vr.br.Reset(r)
+	} else {
+		vr.framing.reset(r)
+		vr.br.Reset(&vr.framing)
+	}
+	return vr
+}
 
-	case c == 'i':
-		var buf []byte
-		buf, err = readSlice(r, 'e')
-		if err != nil {
-			goto exit
-		}
-		var str string
-		var i int64
-		var i2 uint64
-		var f float64
-		str = string(buf)
-		// If the number is exactly an integer, use that.
-		if i, err = strconv.ParseInt(str, 10, 64); err == nil {
-			build.Int64(i)
-		} else if i2, err = strconv.ParseUint(str, 10, 64); err == nil {
-			build.Uint64(i2)
-		} else if f, err = strconv.ParseFloat(str, 64); err == nil {
-			build.Float64(f)
-		} else {
-			err = errors.New("Bad integer")
-		}
+// readFrom sets d up to decode a single value from r. Unless r is a
+// *bufio.Reader already, it is read through a valueReader, which is
+// returned to be released with done once the value has been read.
+func (d *decodeState) readFrom(r io.Reader) *valueReader {
+	if br, ok := r.(*bufio.Reader); ok {
+		d.r = br
+		return nil
+	}
+	vr := newValueReader(r)
+	d.r = &vr.br
+	return vr
+}
 
-	case c == 'l':
-		// array
-		build.Array()
-		n := 0
-		for {
-			c, err = r.ReadByte()
-			if err != nil {
-				goto exit
-			}
-			if c == 'e' {
-				break
-			}
-			err = r.UnreadByte()
-			if err != nil {
-				goto exit
-			}
-			err = parseFromReader(r, build.Elem(n))
-			if err != nil {
-				goto exit
-			}
-			n++
-		}
-	default:
-		err = fmt.Errorf("Unexpected character: '%v'", c)
+// done releases vr, if it is not nil, and returns err, which is the error
+// of decoding the value, or else any error from releasing vr.
+func (vr *valueReader) done(err error) error {
+	if vr == nil {
+		return err
 	}
-exit:
-	build.Flush()
-	return
+	if releaseErr := vr.release(); err == nil {
+		err = releaseErr
+	}
+	return err
 }
 
-// Parse parses the bencode stream and makes calls to
-// the builder to construct a parsed representation.
-func parse(reader io.Reader, builder builder) (err error) {
-	// Check to see if the reader already fulfills the bufio.Reader interface.
-	// Wrap it in a bufio.Reader if it doesn't.
-	r, ok := reader.(*bufio.Reader)
-	if !ok {
-		r = newBufioReader(reader)
-		defer bufioReaderPool.Put(r)
+// release hands back any bytes read past the end of the value and returns
+// vr to the pool.
+func (vr *valueReader) release() (err error) {
+	if n := vr.br.Buffered(); n > 0 && vr.seeker != nil {
+		_, err = vr.seeker.Seek(-int64(n), io.SeekCurrent)
 	}
+	vr.seeker = nil
+	vr.framing.reset(nil)
+	vr.br.Reset(nil)
+	valueReaderPool.Put(vr)
+	return
+}
 
-	return parseFromReader(r, builder)
+// A framingReader reads no further into r than the bencode value being
+// decoded, so that a bufio.Reader on top of it never buffers bytes that
+// belong to whatever follows. It follows just enough of the syntax to know
+// where each string and the value itself end. The contents of a string are
+// read all at once, as their length is known. The framing bytes, such as
+// 'i', 'l', 'd', 'e', digits and ':', are read one at a time: if r has
+// ReadByte, a run of them is returned from a single call to Read, otherwise
+// each takes a call of its own.
+type framingReader struct {
+	r  io.Reader
+	br io.ByteReader
+
+	depth   int  // the lists and dictionaries entered
+	inInt   bool // between the 'i' and 'e' of an integer
+	length  int  // the string length read so far
+	pending int  // the bytes of string contents left to read
 }
 
-var bufioReaderPool sync.Pool
+func (f *framingReader) reset(r io.Reader) {
+	*f = framingReader{r: r}
+	f.br, _ = r.(io.ByteReader)
+}
 
-func newBufioReader(r io.Reader) *bufio.Reader {
-	if v := bufioReaderPool.Get(); v != nil {
-		br := v.(*bufio.Reader)
-		br.Reset(r)
-		return br
+func (f *framingReader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if f.pending > 0 {
+		n, err = io.ReadFull(f.r, p[:min(len(p), f.pending)])
+		f.pending -= n
+		return
+	}
+	if f.br == nil {
+		if n, err = f.r.Read(p[:1]); n == 1 {
+			f.frame(p[0])
+		}
+		return
+	}
+	for n < len(p) {
+		if p[n], err = f.br.ReadByte(); err != nil {
+			return
+		}
+		n++
+		if !f.frame(p[n-1]) {
+			break
+		}
+	}
+	return
+}
+
+// frame follows the syntax through c, the next byte of the value. It
+// reports whether the bytes that follow are framing bytes of the same
+// value. It returns false at the ':' before the contents of a string, so
+// that the parser can check the length before they are read.
+func (f *framingReader) frame(c byte) bool {
+	if f.inInt {
+		f.inInt = c != 'e'
+		return f.inInt || f.depth > 0
+	}
+	if c >= '0' && c <= '9' {
+		// A length too large to be valid is rejected by the parser
+		// before any of the contents are read.
+		if f.length <= (math.MaxInt-9)/10 {
+			f.length = f.length*10 + int(c-'0')
+		}
+		return true
+	}
+	length := f.length
+	f.length = 0
+	switch c {
+	case ':':
+		f.pending = length
+		return false
+	case 'i':
+		f.inInt = true
+		return true
+	case 'l', 'd':
+		f.depth++
+		return true
+	case 'e':
+		if f.depth > 0 {
+			f.depth--
+		}
+		return f.depth > 0
 	}
-	return bufio.NewReader(r)
+	return false
 }
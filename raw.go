@@ -0,0 +1,43 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+)
+
+// RawMessage is a raw encoded bencode value.
+//
+// As a struct field, slice element or map value, a RawMessage receives the
+// exact bytes of the corresponding value during Unmarshal, and those bytes
+// are written unchanged by Marshal. This makes it possible to compute
+// hashes over a sub-value, such as the info dictionary of a torrent:
+//
+//	var torrent struct {
+//		Announce string
+//		Info     bencode.RawMessage "info"
+//	}
+//	err := bencode.Unmarshal(r, &torrent)
+//	infoHash := sha1.Sum(torrent.Info)
+//
+// A nil RawMessage is left out of a dictionary, the same way as a nil
+// interface value.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
+// MarshalBencode returns m as the bencode encoding of m.
+func (m RawMessage) MarshalBencode() ([]byte, error) {
+	if m == nil {
+		return nil, errors.New("bencode: cannot marshal nil RawMessage")
+	}
+	return m, nil
+}
+
+// UnmarshalBencode sets *m to a copy of data.
+func (m *RawMessage) UnmarshalBencode(data []byte) error {
+	if m == nil {
+		return errors.New("bencode: UnmarshalBencode on nil pointer")
+	}
+	*m = append((*m)[0:0], data...)
+	return nil
+}
@@ -0,0 +1,77 @@
+package bencode
+
+import (
+	"bytes"
+	"testing"
+)
+
+type torrentFile struct {
+	Announce string     "announce"
+	Info     RawMessage "info"
+	Comment  RawMessage "comment"
+}
+
+func TestRawMessageRoundTrip(t *testing.T) {
+	// The info dictionary is deliberately not canonical: its keys are out
+	// of order and a string length has a leading zero.
+	const info = "d4:name05:hello6:lengthi12e5:filesld4:pathl1:aeeee"
+	const input = "d8:announce3:url4:info" + info + "e"
+
+	var tf torrentFile
+	if err := Unmarshal(bytes.NewBufferString(input), &tf); err != nil {
+		t.Fatal(err)
+	}
+	if tf.Announce != "url" {
+		t.Fatalf("Announce = %q", tf.Announce)
+	}
+	if string(tf.Info) != info {
+		t.Fatalf("Info = %q, want %q", tf.Info, info)
+	}
+	if tf.Comment != nil {
+		t.Fatalf("Comment = %q, want nil", tf.Comment)
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, tf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Fatalf("Marshal = %q, want %q", buf.String(), input)
+	}
+}
+
+func TestRawMessageMapValues(t *testing.T) {
+	const input = "d1:ai-3e1:bl1:xe1:cd1:ki0eee"
+	var m map[string]RawMessage
+	if err := Unmarshal(bytes.NewBufferString(input), &m); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "i-3e", "b": "l1:xe", "c": "d1:ki0ee"}
+	if len(m) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(m), len(want))
+	}
+	for k, v := range want {
+		if string(m[k]) != v {
+			t.Errorf("m[%q] = %q, want %q", k, m[k], v)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Fatalf("Marshal = %q, want %q", buf.String(), input)
+	}
+}
+
+func TestRawMessageInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Marshal(&buf, []RawMessage{RawMessage("3:ab")}); err == nil {
+		t.Fatal("expected an error marshalling a truncated RawMessage")
+	}
+	var tf torrentFile
+	if err := Unmarshal(bytes.NewBufferString("d4:infod1:ai1e"), &tf); err == nil {
+		t.Fatal("expected an error for a truncated info dictionary")
+	}
+}
@@ -0,0 +1,296 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Validation of encoded values held in memory, such as the output of a
+// Marshaler. The scanner checks syntax without building anything.
+
+// IsCanonical reports whether data holds exactly one bencode value in
+// canonical form: integers and string lengths without leading zeros,
+// signs on zero or fractions, and dictionary keys in strictly ascending
+// order of their raw bytes. Canonical encodings are unique, so two parties
+// hashing the same canonical data always agree.
+func IsCanonical(data []byte) bool {
+	return checkValid(data, true) == nil
+}
+
+// A SyntaxError describes malformed bencode input, or in strict mode input
+// that is not in canonical form. Input that ends in the middle of a value
+// is reported as io.ErrUnexpectedEOF instead.
+type SyntaxError struct {
+	Offset   int64  // offset of the offending byte in the input
+	Byte     byte   // the offending byte
+	Expected string // description of what was expected instead
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("bencode: syntax error at offset %d: unexpected %s, expected %s",
+		e.Offset, quoteByte(e.Byte), e.Expected)
+}
+
+func quoteByte(c byte) string {
+	if c < utf8.RuneSelf {
+		return strconv.QuoteRune(rune(c))
+	}
+	return fmt.Sprintf("byte 0x%02x", c)
+}
+
+func syntaxError(off int, c byte, expected string) error {
+	return &SyntaxError{int64(off), c, expected}
+}
+
+// rebase adds base to the offset of a SyntaxError found in a token that
+// starts at offset base of the input.
+func rebase(err error, base int64) error {
+	if se, ok := err.(*SyntaxError); ok {
+		se.Offset += base
+	}
+	return err
+}
+
+const (
+	expectedValue = "integer, string, list or dictionary"
+	expectedKey   = "string key or 'e'"
+)
+
+// checkValid returns an error unless data holds exactly one well-formed
+// bencode value. If strict is set, the value must also be canonical.
+func checkValid(data []byte, strict bool) error {
+	n, err := scanValue(data, 0, strict, 0)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return syntaxError(n, data[n], "end of input")
+	}
+	return nil
+}
+
+// scanValue scans the value starting at data[i], nested depth levels deep,
+// and returns the index of the first byte after it.
+func scanValue(data []byte, i int, strict bool, depth int) (int, error) {
+	if i >= len(data) {
+		return i, io.ErrUnexpectedEOF
+	}
+	if depth >= maxNesting {
+		return i, &LimitError{"MaxDepth", maxNesting}
+	}
+	switch c := data[i]; {
+	case c == 'i':
+		j, err := scanTo(data, i+1, 'e')
+		if err != nil {
+			return j, err
+		}
+		if strict {
+			err = checkCanonicalInt(data[i+1 : j])
+		} else if !validInteger(data[i+1 : j]) {
+			err = badInteger(data[i+1 : j])
+		}
+		return j + 1, rebase(err, int64(i+1))
+	case c == 'l':
+		i++
+		for {
+			if i >= len(data) {
+				return i, io.ErrUnexpectedEOF
+			}
+			if data[i] == 'e' {
+				return i + 1, nil
+			}
+			var err error
+			if i, err = scanValue(data, i, strict, depth+1); err != nil {
+				return i, err
+			}
+		}
+	case c == 'd':
+		var prevKey []byte
+		i++
+		for n := 1; ; n++ {
+			if i >= len(data) {
+				return i, io.ErrUnexpectedEOF
+			}
+			if data[i] == 'e' {
+				return i + 1, nil
+			}
+			if data[i] < '0' || data[i] > '9' {
+				return i, syntaxError(i, data[i], expectedKey)
+			}
+			keyStart := i
+			var err error
+			if i, err = scanValue(data, i, strict, depth+1); err != nil {
+				return i, err
+			}
+			if strict {
+				key := data[keyStart:i]
+				key = key[bytes.IndexByte(key, ':')+1:]
+				if err = checkKeyOrder(n == 1, prevKey, key); err != nil {
+					return keyStart, rebase(err, int64(i-len(key)))
+				}
+				prevKey = key
+			}
+			if i, err = scanValue(data, i, strict, depth+1); err != nil {
+				return i, err
+			}
+		}
+	case c >= '0' && c <= '9':
+		j, err := scanTo(data, i, ':')
+		if err != nil {
+			return j, err
+		}
+		if strict {
+			if err = checkCanonicalLength(data[i:j]); err != nil {
+				return i, rebase(err, int64(i))
+			}
+		}
+		length, err := strconv.ParseInt(string(data[i:j]), 10, 64)
+		if err != nil {
+			return i, rebase(badLength(data[i:j]), int64(i))
+		}
+		if length > int64(len(data)-j-1) {
+			return len(data), io.ErrUnexpectedEOF
+		}
+		return j + 1 + int(length), nil
+	default:
+		return i, syntaxError(i, c, expectedValue)
+	}
+}
+
+// scanTo returns the index of the first delim at or after data[i].
+func scanTo(data []byte, i int, delim byte) (int, error) {
+	for ; i < len(data); i++ {
+		if data[i] == delim {
+			return i, nil
+		}
+	}
+	return i, io.ErrUnexpectedEOF
+}
+
+// validInteger reports whether buf is an integer that the parsers accept:
+// a decimal integer or, for compatibility, a floating point number.
+func validInteger(buf []byte) bool {
+	digits := buf
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			_, err := strconv.ParseFloat(string(buf), 64)
+			return err == nil
+		}
+	}
+	return true
+}
+
+// The checks below report the offsets of their errors relative to the start
+// of buf, to be rebased by the caller.
+
+// firstNonDigit returns the index of the first byte of buf at or after i
+// that is not a decimal digit, or len(buf).
+func firstNonDigit(buf []byte, i int) int {
+	for i < len(buf) && buf[i] >= '0' && buf[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// byteAt returns buf[i], or delim, the byte ending buf, if i is len(buf).
+func byteAt(buf []byte, i int, delim byte) byte {
+	if i < len(buf) {
+		return buf[i]
+	}
+	return delim
+}
+
+// badDigits returns the error for a token buf, ending with delim, that
+// should be a run of digits after an optional sign, but is not.
+func badDigits(buf []byte, start int, delim byte) error {
+	i := firstNonDigit(buf, start)
+	expected := "digit or " + quoteByte(delim)
+	if i == start {
+		expected = "digit"
+	} else if i == len(buf) {
+		// All digits, but out of range.
+		return syntaxError(start, buf[start], "shorter number")
+	}
+	return syntaxError(i, byteAt(buf, i, delim), expected)
+}
+
+// badInteger returns the error for buf, the text between the 'i' and 'e'
+// of an integer, when it is not a decimal integer.
+func badInteger(buf []byte) error {
+	start := 0
+	if len(buf) > 0 && buf[0] == '-' {
+		start = 1
+	}
+	return badDigits(buf, start, 'e')
+}
+
+// badLength returns the error for buf, the text before the ':' of a
+// string, when it is not a valid length.
+func badLength(buf []byte) error {
+	return badDigits(buf, 0, ':')
+}
+
+// checkCanonicalInt returns an error unless buf, the text between the 'i'
+// and 'e' of an integer, is in canonical form.
+func checkCanonicalInt(buf []byte) error {
+	start := 0
+	if len(buf) > 0 && buf[0] == '-' {
+		start = 1
+	}
+	switch {
+	case !isDigits(buf[start:]):
+		return badInteger(buf)
+	case buf[start] == '0' && len(buf) > start+1:
+		return syntaxError(start+1, buf[start+1], "'e' after a zero")
+	case buf[start] == '0' && start > 0:
+		return syntaxError(start, '0', "non-zero digit after '-'")
+	}
+	return nil
+}
+
+// checkCanonicalLength returns an error unless buf, the text before the
+// ':' of a string, is a canonical length.
+func checkCanonicalLength(buf []byte) error {
+	switch {
+	case !isDigits(buf):
+		return badLength(buf)
+	case buf[0] == '0' && len(buf) > 1:
+		return syntaxError(1, buf[1], "':' after a zero")
+	}
+	return nil
+}
+
+// isDigits reports whether buf is a non-empty run of decimal digits.
+func isDigits(buf []byte) bool {
+	if len(buf) == 0 {
+		return false
+	}
+	for _, c := range buf {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkKeyOrder returns an error unless key is the first key of its
+// dictionary or sorts strictly after prev, the previous key. The offset of
+// the error is relative to the start of key.
+func checkKeyOrder(first bool, prev, key []byte) error {
+	if first || bytes.Compare(prev, key) < 0 {
+		return nil
+	}
+	if len(key) == 0 {
+		return syntaxError(-1, ':', fmt.Sprintf("key sorting after %q", prev))
+	}
+	return syntaxError(0, key[0], fmt.Sprintf("key sorting after %q", prev))
+}
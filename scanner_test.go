@@ -0,0 +1,179 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+var canonicalTests = []struct {
+	s         string
+	canonical bool
+}{
+	{"i0e", true},
+	{"i-1e", true},
+	{"i42e", true},
+	{"0:", true},
+	{"3:abc", true},
+	{"le", true},
+	{"de", true},
+	{"d0:i1e1:ai2e2:aai3e1:bi4ee", true},
+	{"ld1:ai1eeli1eee", true},
+	{"i-0e", false},
+	{"i03e", false},
+	{"i-03e", false},
+	{"i+3e", false},
+	{"i7.5e", false},
+	{"ie", false},
+	{"03:abc", false},
+	{"d1:bi1e1:ai2ee", false},
+	{"d1:ai1e1:ai2ee", false},
+	{"d0:i1e0:i2ee", false},
+	{"ld1:bi1e1:ai2eee", false},
+	{"i1ei2e", false},
+	{"3:ab", false},
+	{"", false},
+}
+
+func TestIsCanonical(t *testing.T) {
+	for _, tt := range canonicalTests {
+		if got := IsCanonical([]byte(tt.s)); got != tt.canonical {
+			t.Errorf("IsCanonical(%q) = %v, want %v", tt.s, got, tt.canonical)
+		}
+	}
+}
+
+func TestDecoderStrict(t *testing.T) {
+	for _, tt := range canonicalTests {
+		if tt.s == "" || tt.s == "i1ei2e" || tt.s == "3:ab" {
+			continue
+		}
+		d := NewDecoder(strings.NewReader(tt.s))
+		d.SetStrict(true)
+		if _, err := d.Decode(); (err == nil) != tt.canonical {
+			t.Errorf("Decode(%q) returned %v", tt.s, err)
+		}
+
+		d = NewDecoder(strings.NewReader(tt.s))
+		d.SetStrict(true)
+		var v any
+		if err := d.DecodeInto(&v); (err == nil) != tt.canonical {
+			t.Errorf("DecodeInto(%q) returned %v", tt.s, err)
+		}
+	}
+}
+
+func TestDecoderStrictErrors(t *testing.T) {
+	tests := []struct {
+		s   string
+		err SyntaxError
+	}{
+		{"d1:bi1e1:ai2ee", SyntaxError{9, 'a', `key sorting after "b"`}},
+		{"d1:ai1e1:ai2ee", SyntaxError{9, 'a', `key sorting after "a"`}},
+		{"d1:ai1e0:i2ee", SyntaxError{8, ':', `key sorting after "a"`}},
+		{"d0:i1e0:i2ee", SyntaxError{7, ':', `key sorting after ""`}},
+		{"i-0e", SyntaxError{2, '0', "non-zero digit after '-'"}},
+		{"i03e", SyntaxError{2, '3', "'e' after a zero"}},
+		{"i1.5e", SyntaxError{2, '.', "digit or 'e'"}},
+		{"l01:ae", SyntaxError{2, '1', "':' after a zero"}},
+	}
+	for _, tt := range tests {
+		for _, d := range []*Decoder{NewDecoder(strings.NewReader(tt.s)), NewBytesDecoder([]byte(tt.s))} {
+			d.SetStrict(true)
+			var v map[string]any
+			checkSyntaxError(t, "DecodeInto("+tt.s+")", d.DecodeInto(&v), tt.err)
+		}
+		checkSyntaxError(t, "checkValid("+tt.s+")", checkValid([]byte(tt.s), true), tt.err)
+	}
+}
+
+func TestDecoderStrictRawMessage(t *testing.T) {
+	var tf torrentFile
+	d := NewDecoder(strings.NewReader("d4:infod1:bi1e1:ai2eee"))
+	d.SetStrict(true)
+	if err := d.DecodeInto(&tf); err == nil {
+		t.Fatal("expected an error for a non-canonical RawMessage in strict mode")
+	}
+}
+
+func TestEncoderCanonicalRawMessage(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetCanonical(true)
+	if err := enc.Encode(torrentFile{Info: RawMessage("d1:bi1e1:ai2ee")}); err == nil {
+		t.Fatal("expected an error for a non-canonical RawMessage")
+	}
+	if err := enc.Encode(torrentFile{Info: RawMessage("d1:ai2e1:bi1ee")}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkSyntaxError(t *testing.T, what string, err error, want SyntaxError) {
+	t.Helper()
+	var se *SyntaxError
+	if !errors.As(err, &se) || *se != want {
+		t.Errorf("%s returned %v, want %v", what, err, &want)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		s   string
+		err SyntaxError
+	}{
+		{"x", SyntaxError{0, 'x', "integer, string, list or dictionary"}},
+		{"li1exe", SyntaxError{4, 'x', "integer, string, list or dictionary"}},
+		{"di1ei2ee", SyntaxError{1, 'i', "string key or 'e'"}},
+		{"ie", SyntaxError{1, 'e', "digit"}},
+		{"i-e", SyntaxError{2, 'e', "digit"}},
+		{"i12ae", SyntaxError{3, 'a', "digit or 'e'"}},
+		{"l-3:abce", SyntaxError{1, '-', "integer, string, list or dictionary"}},
+		{"l3x:abce", SyntaxError{2, 'x', "digit or ':'"}},
+		{"99999999999999999999:a", SyntaxError{0, '9', "shorter number"}},
+		{"i1ei2e", SyntaxError{3, 'i', "end of input"}},
+	}
+	for _, tt := range tests {
+		_, err := DecodeBytes([]byte(tt.s))
+		checkSyntaxError(t, "DecodeBytes("+tt.s+")", err, tt.err)
+		var v struct{ A []int }
+		checkSyntaxError(t, "UnmarshalBytes("+tt.s+")", UnmarshalBytes([]byte(tt.s), &v), tt.err)
+		if tt.err.Expected == "end of input" {
+			continue
+		}
+		_, err = Decode(strings.NewReader(tt.s))
+		checkSyntaxError(t, "Decode("+tt.s+")", err, tt.err)
+		checkSyntaxError(t, "Unmarshal("+tt.s+")", Unmarshal(strings.NewReader(tt.s), &v), tt.err)
+		checkSyntaxError(t, "checkValid("+tt.s+")", checkValid([]byte(tt.s), false), tt.err)
+	}
+
+	// Offsets count from the start of the stream.
+	d := NewDecoder(strings.NewReader("i1e3:abci2xe"))
+	for d.More() {
+		if _, err := d.Decode(); err != nil {
+			checkSyntaxError(t, "Decoder", err, SyntaxError{10, 'x', "digit or 'e'"})
+			break
+		}
+	}
+
+	want := "bencode: syntax error at offset 3: unexpected 'x', expected digit or ':'"
+	if err := (&SyntaxError{3, 'x', "digit or ':'"}); err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestScannerUnexpectedEOF(t *testing.T) {
+	for _, s := range []string{"i12", "l", "d1:a", "3:ab", "d1:ai1e"} {
+		if err := checkValid([]byte(s), false); err != io.ErrUnexpectedEOF {
+			t.Errorf("checkValid(%q) = %v, want io.ErrUnexpectedEOF", s, err)
+		}
+		var v Value
+		if err := v.UnmarshalBencode([]byte(s)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Value.UnmarshalBencode(%q) = %v, want io.ErrUnexpectedEOF", s, err)
+		}
+		if err := Marshal(io.Discard, RawMessage(s)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Marshal(RawMessage(%q)) = %v, want io.ErrUnexpectedEOF", s, err)
+		}
+	}
+}
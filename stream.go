@@ -0,0 +1,208 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+)
+
+// A Decoder reads and decodes a sequence of bencode values from an input
+// stream, such as a network connection or a log file of concatenated values.
+//
+// The Decoder buffers its input, so it may read data from r beyond the
+// value most recently decoded. Those bytes are kept for the next call and
+// are available through Buffered.
+type Decoder struct {
+	cr   countingReader
+	ds   decodeState
+	opts decodeOptions
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{}
+	d.cr.r = r
+	d.ds.r = bufio.NewReader(&d.cr)
+	d.opts.limits = DefaultLimits
+	d.ds.opts = &d.opts
+	return d
+}
+
+// NewBytesDecoder returns a new decoder that reads the values held in
+// data. It parses data in place, which is faster than a Decoder reading
+// from a bytes.Reader, and it can be set to return []byte results that
+// alias data, see SetAliasInput.
+func NewBytesDecoder(data []byte) *Decoder {
+	d := &Decoder{}
+	d.ds.data = data
+	d.opts.limits = DefaultLimits
+	d.ds.opts = &d.opts
+	return d
+}
+
+// Decode reads the next bencode value from the input and returns its
+// generic representation, as described for the package level Decode.
+func (d *Decoder) Decode() (data interface{}, err error) {
+	return d.ds.decode()
+}
+
+// DecodeInto reads the next bencode value from the input and stores it in
+// the value pointed to by val, following the rules of Unmarshal.
+func (d *Decoder) DecodeInto(val interface{}) (err error) {
+	v, err := unmarshalTarget(val)
+	if err != nil {
+		return
+	}
+	err = d.ds.unmarshal(v)
+	return
+}
+
+// SetStrict controls whether the Decoder rejects input that is not in
+// canonical form, as described for IsCanonical. In strict mode integers
+// and string lengths with leading zeros, "-0", fractional integers, and
+// dictionaries with duplicate or unsorted keys are reported as errors.
+func (d *Decoder) SetStrict(on bool) {
+	d.opts.strict = on
+}
+
+// SetByteStrings controls whether Decode returns bencode strings as []byte
+// rather than string, which suits binary fields such as "pieces", "peers"
+// and "id". Dictionary keys are still returned as the string keys of
+// map[string]interface{}. The resulting trees round-trip through Marshal,
+// which encodes []byte as a bencode string.
+func (d *Decoder) SetByteStrings(on bool) {
+	d.opts.byteStrings = on
+}
+
+// SetLimits sets the resource limits applied to each value decoded from
+// the stream, replacing DefaultLimits. A Decoder reading untrusted input,
+// such as datagrams from the network, should set all of them.
+func (d *Decoder) SetLimits(l Limits) {
+	d.opts.limits = l
+}
+
+// UseNumber causes the Decoder to return integers as Number rather than as
+// int64 or *big.Int, both from Decode and for interface values filled in
+// by DecodeInto.
+func (d *Decoder) UseNumber() {
+	d.opts.useNumber = true
+}
+
+// DisallowUnknownFields causes DecodeInto to return an error when a
+// dictionary decoded into a struct has a key that matches no field of the
+// struct, rather than discarding the entry.
+func (d *Decoder) DisallowUnknownFields() {
+	d.opts.disallowUnknownFields = true
+}
+
+// SetAliasInput controls whether a Decoder created by NewBytesDecoder
+// returns []byte results, including RawMessage values and the strings
+// returned by SetByteStrings, as slices of its input rather than copies.
+// This saves an allocation and a copy per string, but the results must
+// not be used after the input is modified. The setting has no effect on
+// a Decoder reading from an io.Reader.
+func (d *Decoder) SetAliasInput(on bool) {
+	d.opts.alias = on
+}
+
+// More reports whether there is another value available in the input, or
+// after a ListStart or DictStart returned by Token, in the current list or
+// dictionary.
+func (d *Decoder) More() bool {
+	var c byte
+	if d.ds.r != nil {
+		buf, err := d.ds.r.Peek(1)
+		if err != nil {
+			return false
+		}
+		c = buf[0]
+	} else if d.ds.off < len(d.ds.data) {
+		c = d.ds.data[d.ds.off]
+	} else {
+		return false
+	}
+	return len(d.ds.tokens) == 0 || c != 'e'
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer.
+// The reader is valid until the next call to Decode or DecodeInto.
+func (d *Decoder) Buffered() io.Reader {
+	if d.ds.r == nil {
+		return bytes.NewReader(d.ds.data[d.ds.off:])
+	}
+	buf, _ := d.ds.r.Peek(d.ds.r.Buffered())
+	return bytes.NewReader(buf)
+}
+
+// InputOffset returns the offset of the current decoder position in the
+// input stream: the number of bytes consumed by the values decoded so far.
+func (d *Decoder) InputOffset() int64 {
+	if d.ds.r == nil {
+		return int64(d.ds.off)
+	}
+	return d.cr.n - int64(d.ds.r.Buffered())
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.n += int64(n)
+	return
+}
+
+// An Encoder writes bencode values to an output stream.
+//
+// Each value is encoded into an internal buffer and written to the
+// underlying writer with a single call to Write, so an Encoder may be used
+// directly on an unbuffered connection. The buffer is kept between calls,
+// which makes an Encoder cheap to reuse for many messages.
+type Encoder struct {
+	w io.Writer
+	e encodeState
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the bencode encoding of val to the stream, following the
+// rules of Marshal and the options set on the Encoder. Nothing is written
+// if an error occurs.
+func (enc *Encoder) Encode(val interface{}) (err error) {
+	e := &enc.e
+	e.buf = e.buf[:0]
+	for k := range e.ptrSeen {
+		delete(e.ptrSeen, k)
+	}
+	if err = e.writeValue(reflect.ValueOf(val)); err != nil {
+		return
+	}
+	_, err = enc.w.Write(e.buf)
+	return
+}
+
+// SetCanonical controls whether the Encoder refuses to produce output that
+// is not in canonical form, such as a Marshaler or RawMessage whose bytes
+// are not canonical, or a Number with leading zeros.
+func (enc *Encoder) SetCanonical(on bool) {
+	enc.e.canonical = on
+}
+
+// SetFloatPolicy sets how floating point values are written.
+// The default is FloatError.
+func (enc *Encoder) SetFloatPolicy(p FloatPolicy) {
+	enc.e.floatPolicy = p
+}
+
+// SetCycleDetection controls whether the Encoder checks for cyclic data
+// structures, returning an error rather than recursing forever.
+func (enc *Encoder) SetCycleDetection(on bool) {
+	enc.e.detectCycles = on
+}
@@ -0,0 +1,342 @@
+package bencode
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDecoderSequence(t *testing.T) {
+	input := "i1e3:abcli2eed1:ai3ee"
+	d := NewDecoder(strings.NewReader(input))
+	var got []any
+	for d.More() {
+		v, err := d.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	want := []any{1, "abc", []any{2}, map[string]any{"a": 3}}
+	if err := checkFuzzyEqual(want, got); err != nil {
+		t.Fatal(err)
+	}
+	if off := d.InputOffset(); off != int64(len(input)) {
+		t.Fatalf("InputOffset = %d, want %d", off, len(input))
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Fatalf("Decode at end of input returned %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderDecodeInto(t *testing.T) {
+	d := NewDecoder(strings.NewReader("d1:ai10e1:b3:fooed1:ai20e1:b3:baretrailing"))
+	var a1, a2 structA
+	if err := d.DecodeInto(&a1); err != nil {
+		t.Fatal(err)
+	}
+	if off := d.InputOffset(); off != 17 {
+		t.Fatalf("InputOffset = %d, want 17", off)
+	}
+	if err := d.DecodeInto(&a2); err != nil {
+		t.Fatal(err)
+	}
+	if a1.A != 10 || a1.B != "foo" || a2.A != 20 || a2.B != "bar" {
+		t.Fatalf("got %+v and %+v", a1, a2)
+	}
+	rest, _ := io.ReadAll(d.Buffered())
+	if string(rest) != "trailing" {
+		t.Fatalf("Buffered = %q, want %q", rest, "trailing")
+	}
+}
+
+func TestBytesDecoder(t *testing.T) {
+	input := []byte("i1e3:abcd1:ai10e1:b3:fooetrailing")
+	d := NewBytesDecoder(input)
+	v, err := d.Decode()
+	if err != nil || v != int64(1) {
+		t.Fatalf("Decode = %v, %v", v, err)
+	}
+	var s string
+	if err = d.DecodeInto(&s); err != nil || s != "abc" {
+		t.Fatalf("DecodeInto = %q, %v", s, err)
+	}
+	var a structA
+	if err = d.DecodeInto(&a); err != nil || a.A != 10 || a.B != "foo" {
+		t.Fatalf("DecodeInto = %+v, %v", a, err)
+	}
+	if off := d.InputOffset(); off != 25 {
+		t.Fatalf("InputOffset = %d, want 25", off)
+	}
+	rest, _ := io.ReadAll(d.Buffered())
+	if string(rest) != "trailing" {
+		t.Fatalf("Buffered = %q, want %q", rest, "trailing")
+	}
+}
+
+type aliasTarget struct {
+	Data []byte     "data"
+	Raw  RawMessage "raw"
+}
+
+func TestBytesDecoderAliasInput(t *testing.T) {
+	for _, alias := range []bool{false, true} {
+		input := []byte("d4:data3:abc3:rawli1eee")
+		d := NewBytesDecoder(input)
+		d.SetAliasInput(alias)
+		var v aliasTarget
+		if err := d.DecodeInto(&v); err != nil {
+			t.Fatal(err)
+		}
+		if string(v.Data) != "abc" || string(v.Raw) != "li1ee" {
+			t.Fatalf("got %q and %q", v.Data, v.Raw)
+		}
+		copy(input, bytes.ToUpper(input))
+		if aliased := string(v.Data) == "ABC" && string(v.Raw) == "LI1EE"; aliased != alias {
+			t.Errorf("alias %v: results alias input: %v", alias, aliased)
+		}
+
+		d = NewBytesDecoder([]byte("l3:abce"))
+		d.SetAliasInput(alias)
+		d.SetByteStrings(true)
+		g, err := d.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := g.([]interface{})[0].([]byte); string(b) != "abc" || alias && cap(b) != 3 {
+			t.Errorf("alias %v: got %q with capacity %d", alias, b, cap(b))
+		}
+	}
+}
+
+func TestDecodeBytesErrors(t *testing.T) {
+	if _, err := DecodeBytes([]byte("i1ei2e")); err == nil {
+		t.Error("DecodeBytes accepted trailing data")
+	}
+	var i int
+	if err := UnmarshalBytes([]byte("i1ei2e"), &i); err == nil {
+		t.Error("UnmarshalBytes accepted trailing data")
+	}
+	for _, input := range []string{"", "l", "i12", "5:abc", "d1:a"} {
+		want := io.ErrUnexpectedEOF
+		if input == "" {
+			want = io.EOF
+		}
+		if _, err := DecodeBytes([]byte(input)); err != want {
+			t.Errorf("DecodeBytes(%q) = %v, want %v", input, err, want)
+		}
+		var v aliasTarget
+		if err := UnmarshalBytes([]byte(input), &v); err != want {
+			t.Errorf("UnmarshalBytes(%q) = %v, want %v", input, err, want)
+		}
+	}
+}
+
+// onlyReader hides every method of the wrapped reader except Read.
+type onlyReader struct {
+	r io.Reader
+}
+
+func (o onlyReader) Read(p []byte) (int, error) { return o.r.Read(p) }
+
+func TestDecodeDoesNotOverRead(t *testing.T) {
+	const input = "d1:ai10e1:b3:fooei42e"
+	readers := map[string]func() io.Reader{
+		"seeker": func() io.Reader { return strings.NewReader(input) },
+		"buffer": func() io.Reader { return bytes.NewBufferString(input) },
+		"plain":  func() io.Reader { return onlyReader{strings.NewReader(input)} },
+	}
+	for name, newReader := range readers {
+		r := newReader()
+		var a structA
+		if err := Unmarshal(r, &a); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		v, err := Decode(r)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if v != int64(42) {
+			t.Fatalf("%s: second value = %v, want 42", name, v)
+		}
+	}
+}
+
+// readCounter counts the calls made to Read. It hides any other methods
+// of r, such as ReadByte and Seek.
+type readCounter struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+func TestDecodeReadsStringsWhole(t *testing.T) {
+	contents := strings.Repeat("x", 1<<20)
+	input := "d4:data" + strconv.Itoa(len(contents)) + ":" + contents + "ei42e"
+	r := &readCounter{r: strings.NewReader(input)}
+	var v struct {
+		Data string `data`
+	}
+	if err := Unmarshal(r, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Data != contents {
+		t.Fatal("Unmarshal returned the wrong contents")
+	}
+	// One read for each of the 14 framing bytes, and one for each of the
+	// growing chunks the contents are read in.
+	if r.reads > 32 {
+		t.Errorf("Unmarshal made %d calls to Read", r.reads)
+	}
+	r.reads = 0
+	if n, err := Decode(r); err != nil || n != int64(42) {
+		t.Fatalf("Decode = %v, %v, want 42", n, err)
+	}
+	if r.reads != 4 {
+		t.Errorf("Decode made %d calls to Read, want 4", r.reads)
+	}
+}
+
+// countingWriter counts the calls made to Write.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.Buffer.Write(p)
+}
+
+func TestEncoderSingleWrite(t *testing.T) {
+	var w countingWriter
+	enc := NewEncoder(&w)
+	for i := 0; i < 3; i++ {
+		if err := enc.Encode(structNested{"aa", "q", "ping", unmarshalInnerDict}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const want = "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe"
+	if w.String() != want+want+want {
+		t.Fatalf("got %q", w.String())
+	}
+	if w.writes != 3 {
+		t.Fatalf("Encode made %d calls to Write, want 3", w.writes)
+	}
+}
+
+func TestEncoderFloatPolicy(t *testing.T) {
+	tests := []struct {
+		policy FloatPolicy
+		val    any
+		want   string
+	}{
+		{FloatTruncate, 7.9, "i7e"},
+		{FloatTruncate, float32(-2.5), "i-2e"},
+		{FloatString, 0.5, "3:0.5"},
+		{FloatString, []float64{1, 1e21}, "l1:15:1e+21e"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetFloatPolicy(tt.policy)
+		if err := enc.Encode(tt.val); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("policy %d: Encode(%v) = %q, want %q", tt.policy, tt.val, buf.String(), tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(1.5); err == nil {
+		t.Error("expected an error encoding a float with the default policy")
+	}
+}
+
+func TestEncoderCycleDetection(t *testing.T) {
+	m := map[string]any{"a": 1}
+	m["self"] = m
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetCycleDetection(true)
+	if err := enc.Encode(m); err == nil {
+		t.Fatal("expected an error encoding a cyclic map")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Encode wrote %q after an error", buf.String())
+	}
+
+	// The same slice appearing twice is not a cycle.
+	shared := []any{1}
+	if err := enc.Encode([]any{shared, shared}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "lli1eeli1eee" {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+func TestDecoderByteStrings(t *testing.T) {
+	const input = "d2:id3:\x00\xff\x015:peersl2:ab0:ee"
+	d := NewDecoder(strings.NewReader(input))
+	d.SetByteStrings(true)
+	v, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})
+	if id, ok := m["id"].([]byte); !ok || !bytes.Equal(id, []byte{0, 0xff, 1}) {
+		t.Fatalf("id = %#v", m["id"])
+	}
+	peers := m["peers"].([]interface{})
+	if p, ok := peers[1].([]byte); !ok || len(p) != 0 {
+		t.Fatalf("peers[1] = %#v", peers[1])
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Fatalf("Marshal = %q, want %q", buf.String(), input)
+	}
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	type file struct {
+		Length int64 "length"
+	}
+	type info struct {
+		Files []file "files"
+	}
+	input := "d5:filesld6:lengthi1eed3:md51:x6:lengthi2eeee" + "d5:filesld6:lengthi3eeee"
+	for _, d := range []*Decoder{NewDecoder(strings.NewReader(input)), NewBytesDecoder([]byte(input))} {
+		d.DisallowUnknownFields()
+		var v info
+		err := d.DecodeInto(&v)
+		want := `bencode: unknown field "files[1].md5"`
+		if err == nil || err.Error() != want {
+			t.Errorf("DecodeInto = %v, want %s", err, want)
+		}
+		if len(v.Files) != 2 || v.Files[1].Length != 2 {
+			t.Errorf("DecodeInto stored %+v", v)
+		}
+		// The rest of the value was consumed, so the stream can go on.
+		v = info{}
+		if err = d.DecodeInto(&v); err != nil || len(v.Files) != 1 || v.Files[0].Length != 3 {
+			t.Errorf("second DecodeInto = %+v, %v", v, err)
+		}
+	}
+
+	var v info
+	if err := Unmarshal(strings.NewReader("d3:md51:x5:filesleee"), &v); err != nil {
+		t.Errorf("Unmarshal without DisallowUnknownFields = %v", err)
+	}
+}
@@ -10,227 +10,556 @@
 package bencode
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"reflect"
-	"sort"
+	"strconv"
 	"strings"
 )
 
-type structBuilder struct {
-	val reflect.Value
-
-	// if map_ != nil, write val to map_[key] on each change
-	map_ reflect.Value
-	key  reflect.Value
+// indirect walks down v, allocating nil pointers, until it reaches a value
+// that is not a pointer. It stops early at a value whose pointer implements
+// Unmarshaler and returns that as well. Interfaces are followed if they
+// hold a non-nil pointer, and are otherwise returned as is. An invalid
+// result means that v cannot be set.
+func indirect(v reflect.Value) (reflect.Value, Unmarshaler) {
+	for {
+		switch v.Kind() {
+		case reflect.Interface:
+			if e := v.Elem(); !v.IsNil() && e.Kind() == reflect.Ptr && !e.IsNil() {
+				v = e
+				continue
+			}
+			if v.CanSet() {
+				return v, nil
+			}
+			return reflect.Value{}, nil
+		case reflect.Ptr:
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, nil
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			if u := asUnmarshaler(v); u != nil {
+				return v, u
+			}
+			v = v.Elem()
+		default:
+			if v.CanAddr() {
+				if u := asUnmarshaler(v.Addr()); u != nil {
+					return v, u
+				}
+			}
+			if !v.CanSet() {
+				return reflect.Value{}, nil
+			}
+			return v, nil
+		}
+	}
 }
 
-var nobuilder *structBuilder
-
-func isfloat(v reflect.Value) bool {
-	switch v.Kind() {
-	case reflect.Float32, reflect.Float64:
-		return true
+func asUnmarshaler(p reflect.Value) Unmarshaler {
+	if p.Type().NumMethod() == 0 || !p.CanInterface() {
+		return nil
 	}
-	return false
+	u, _ := p.Interface().(Unmarshaler)
+	return u
 }
 
-func setfloat(v reflect.Value, f float64) {
-	switch v.Kind() {
-	case reflect.Float32, reflect.Float64:
-		v.SetFloat(f)
+// value decodes the next value into v. Values that do not fit v are
+// checked and discarded, recording an UnmarshalTypeError.
+func (d *decodeState) value(v reflect.Value) error {
+	v, u := indirect(v)
+	if u != nil {
+		raw, err := d.readRaw()
+		if err != nil {
+			return err
+		}
+		if m, ok := u.(*RawMessage); ok && d.alias() {
+			*m = raw
+			return nil
+		}
+		return u.UnmarshalBencode(raw)
+	}
+	if !v.IsValid() {
+		return d.skip()
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		x, err := d.any()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(x))
+		return nil
 	}
-}
 
-func setint(val reflect.Value, i int64) {
-	switch v := val; v.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		v.SetInt(int64(i))
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		v.SetUint(uint64(i))
-	case reflect.Interface:
-		v.Set(reflect.ValueOf(i))
-	default:
-		panic("setint called for bogus type: " + val.Kind().String())
+	d.valueOff = d.pos()
+	c, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c == 'i':
+		return d.integer(v)
+	case c == 'l':
+		return d.list(v)
+	case c == 'd':
+		return d.dict(v)
+	case c >= '0' && c <= '9':
+		if err = d.unreadByte(); err != nil {
+			return err
+		}
+		return d.string(v)
 	}
+	return d.unexpected(c, expectedValue)
 }
 
-// If updating b.val is not enough to update the original,
-// copy a changed b.val out to the original.
-func (b *structBuilder) Flush() {
-	if b == nil {
-		return
-	}
-	if b.map_.IsValid() {
-		b.map_.SetMapIndex(b.key, b.val)
+// skip reads and discards the next value, checking its syntax.
+func (d *decodeState) skip() error {
+	c, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c == 'i':
+		_, err = d.readInteger()
+		return err
+	case c == 'l':
+		return d.skipList()
+	case c == 'd':
+		return d.skipDict()
+	case c >= '0' && c <= '9':
+		if err = d.unreadByte(); err != nil {
+			return err
+		}
+		_, err = d.readString()
+		return err
 	}
+	return d.unexpected(c, expectedValue)
 }
 
-func (b *structBuilder) Int64(i int64) {
-	if b == nil {
-		return
-	}
-	if !b.val.CanSet() {
-		x := 0
-		b.val = reflect.ValueOf(&x).Elem()
+// skipList discards the rest of a list whose 'l' has been read.
+func (d *decodeState) skipList() error {
+	if err := d.enter(); err != nil {
+		return err
 	}
-	v := b.val
-	if isfloat(v) {
-		setfloat(v, float64(i))
-	} else {
-		setint(v, i)
+	defer d.leave()
+	for n := 1; ; n++ {
+		end, err := d.atEnd()
+		if end || err != nil {
+			return err
+		}
+		if err = d.checkEntries(n); err != nil {
+			return err
+		}
+		if err = d.skip(); err != nil {
+			return err
+		}
 	}
 }
 
-func (b *structBuilder) Uint64(i uint64) {
-	if b == nil {
-		return
-	}
-	if !b.val.CanSet() {
-		x := uint64(0)
-		b.val = reflect.ValueOf(&x).Elem()
+// skipDict discards the rest of a dictionary whose 'd' has been read.
+func (d *decodeState) skipDict() error {
+	if err := d.enter(); err != nil {
+		return err
 	}
-	v := b.val
-	if isfloat(v) {
-		setfloat(v, float64(i))
-	} else {
-		setint(v, int64(i))
+	defer d.leave()
+	var prevKey []byte
+	for n := 1; ; n++ {
+		end, err := d.atEnd()
+		if end || err != nil {
+			return err
+		}
+		if err = d.checkEntries(n); err != nil {
+			return err
+		}
+		key, err := d.readKey()
+		if err != nil {
+			return err
+		}
+		if d.opts.strict {
+			if err = d.checkKeyOrder(n == 1, prevKey, key); err != nil {
+				return err
+			}
+			prevKey = append(prevKey[:0], key...)
+		}
+		if err = d.skip(); err != nil {
+			return err
+		}
 	}
 }
 
-func (b *structBuilder) Float64(f float64) {
-	if b == nil {
-		return
-	}
-	if !b.val.CanSet() {
-		x := float64(0)
-		b.val = reflect.ValueOf(&x).Elem()
+// integer decodes an integer whose 'i' has been read. For compatibility,
+// integers that overflow int64 are taken as uint64, and floating point
+// numbers are accepted as well.
+func (d *decodeState) integer(v reflect.Value) error {
+	buf, err := d.readInteger()
+	if err != nil {
+		return err
+	}
+	switch v.Type() {
+	case numberType:
+		// Fractional integers, accepted for compatibility, have no
+		// exact value to store.
+		if !isInteger(buf) {
+			d.typeError("integer "+string(buf), v.Type())
+			return nil
+		}
+		v.SetString(string(buf))
+		return nil
+	case bigIntType:
+		x, ok := new(big.Int).SetString(string(buf), 10)
+		if !ok {
+			d.typeError("integer "+string(buf), v.Type())
+			return nil
+		}
+		v.Addr().Interface().(*big.Int).Set(x)
+		return nil
 	}
-	v := b.val
-	if isfloat(v) {
-		setfloat(v, f)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+	default:
+		d.typeError("integer", v.Type())
+		return nil
+	}
+	// If the number is exactly an integer, use that. Values that do not
+	// fit v are reported and leave it unchanged.
+	var ok bool
+	if i, isInt := parseInteger(buf); isInt {
+		ok = d.setInt(v, i)
+	} else if i, err := strconv.ParseInt(string(buf), 10, 64); err == nil {
+		ok = d.setInt(v, i)
+	} else if u, err := strconv.ParseUint(string(buf), 10, 64); err == nil {
+		ok = d.setUint(v, u)
+	} else if f, err := strconv.ParseFloat(string(buf), 64); err == nil {
+		ok = d.setFloat(v, f)
 	} else {
-		setint(v, int64(f))
+		return d.tokenError(badInteger(buf), buf)
+	}
+	if !ok {
+		d.typeError("integer "+string(buf), v.Type())
 	}
+	return nil
 }
 
-func (b *structBuilder) String(s string) {
-	if b == nil {
-		return
+// setInt stores i in v, reporting false if it is out of range.
+func (d *decodeState) setInt(v reflect.Value, i int64) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(i) {
+			return false
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return false
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(i))
+	case reflect.Bool:
+		// Integers other than 0 and 1 are taken as true, unless the
+		// decoder is strict.
+		if (i < 0 || i > 1) && d.opts.strict {
+			return false
+		}
+		v.SetBool(i != 0)
 	}
+	return true
+}
 
-	switch b.val.Kind() {
-	case reflect.String:
-		if !b.val.CanSet() {
-			x := ""
-			b.val = reflect.ValueOf(&x).Elem()
-
+// setUint stores u, which is too large for an int64, in v, reporting false
+// if it is out of range.
+func (d *decodeState) setUint(v reflect.Value, u uint64) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.OverflowUint(u) {
+			return false
 		}
-		b.val.SetString(s)
-	case reflect.Interface:
-		b.val.Set(reflect.ValueOf(s))
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(u))
+	case reflect.Bool:
+		if d.opts.strict {
+			return false
+		}
+		v.SetBool(true)
 	}
+	return true
 }
 
-func (b *structBuilder) Array() {
-	if b == nil {
-		return
-	}
-	if v := b.val; v.Kind() == reflect.Slice {
-		if v.IsNil() {
-			v.Set(reflect.MakeSlice(v.Type(), 0, 8))
+// setFloat stores f in v, truncating it for integer types, and reports
+// false if it is out of range.
+func (d *decodeState) setFloat(v reflect.Value, f float64) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = math.Trunc(f)
+		if f < math.MinInt64 || f >= -math.MinInt64 || v.OverflowInt(int64(f)) {
+			return false
+		}
+		v.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = math.Trunc(f)
+		if f < 0 || f >= 2*-math.MinInt64 || v.OverflowUint(uint64(f)) {
+			return false
 		}
+		v.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		if v.OverflowFloat(f) {
+			return false
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		v.SetBool(f != 0)
 	}
+	return true
 }
 
-func (b *structBuilder) Elem(i int) builder {
-	if b == nil || i < 0 {
-		return nobuilder
+// string decodes a string into a string, byte slice or byte array.
+func (d *decodeState) string(v reflect.Value) error {
+	buf, err := d.readString()
+	if err != nil {
+		return err
+	}
+	switch {
+	case v.Kind() == reflect.String && v.Type() != numberType:
+		v.SetString(string(buf))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		if !d.alias() {
+			buf = bytes.Clone(buf)
+		}
+		v.SetBytes(buf)
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		if len(buf) != v.Len() {
+			d.typeError(strconv.Itoa(len(buf))+"-byte string", v.Type())
+			break
+		}
+		if v.Type().Elem() == byteType {
+			reflect.Copy(v, reflect.ValueOf(buf))
+			break
+		}
+		// reflect.Copy does not convert to named byte types, but v
+		// is settable, so its bytes can be written through a slice.
+		copy(v.Slice(0, v.Len()).Bytes(), buf)
+	default:
+		d.typeError("string", v.Type())
 	}
-	switch v := b.val; v.Kind() {
-	case reflect.Array:
-		if i < v.Len() {
-			return &structBuilder{val: v.Index(i)}
+	return nil
+}
+
+// list decodes a list whose 'l' has been read into a slice or array.
+// A slice is resized to the length of the list; array elements beyond it
+// are zeroed and list elements beyond the array are discarded.
+func (d *decodeState) list(v reflect.Value) error {
+	kind := v.Kind()
+	if kind != reflect.Slice && kind != reflect.Array {
+		d.typeError("list", v.Type())
+		return d.skipList()
+	}
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	n := len(d.path)
+	i := 0
+	for ; ; i++ {
+		end, err := d.atEnd()
+		if err != nil {
+			return err
 		}
-	case reflect.Slice:
-		if i >= v.Cap() {
-			n := v.Cap()
-			if n < 8 {
-				n = 8
-			}
-			for n <= i {
-				n *= 2
-			}
-			nv := reflect.MakeSlice(v.Type(), v.Len(), n)
-			reflect.Copy(nv, v)
-			v.Set(nv)
+		if end {
+			break
+		}
+		if err = d.checkEntries(i + 1); err != nil {
+			return err
 		}
-		if v.Len() <= i && i < v.Cap() {
+		if kind == reflect.Slice && i >= v.Len() {
+			if i >= v.Cap() {
+				v.Grow(1)
+			}
 			v.SetLen(i + 1)
+			v.Index(i).SetZero()
 		}
 		if i < v.Len() {
-			return &structBuilder{val: v.Index(i)}
+			d.path = append(d.path[:n], pathElem{index: i})
+			err = d.value(v.Index(i))
+		} else {
+			err = d.skip()
+		}
+		if err != nil {
+			return err
 		}
 	}
-	return nobuilder
+	d.path = d.path[:n]
+	if kind == reflect.Array {
+		for ; i < v.Len(); i++ {
+			v.Index(i).SetZero()
+		}
+		return nil
+	}
+	if i < v.Len() {
+		v.SetLen(i)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	return nil
 }
 
-func (b *structBuilder) Map() {
-	if b == nil {
-		return
-	}
-	if v := b.val; v.Kind() == reflect.Ptr {
+// dict decodes a dictionary whose 'd' has been read into a struct or a map
+// with string keys.
+func (d *decodeState) dict(v reflect.Value) error {
+	var fields *structFields
+	var key, elem reflect.Value
+	t := v.Type()
+	switch {
+	case v.Kind() == reflect.Struct && t != bigIntType:
+		fields = cachedTypeFields(t)
+	case v.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
 		if v.IsNil() {
-			v.Set(reflect.Zero(v.Type().Elem()).Addr())
-			b.Flush()
+			v.Set(reflect.MakeMap(t))
 		}
-		b.map_ = reflect.Value{}
-		b.val = v.Elem()
+		key = reflect.New(t.Key()).Elem()
+		elem = reflect.New(t.Elem()).Elem()
+	default:
+		d.typeError("dictionary", t)
+		return d.skipDict()
 	}
-	if v := b.val; v.Kind() == reflect.Map && v.IsNil() {
-		v.Set(reflect.MakeMap(v.Type()))
+	if err := d.enter(); err != nil {
+		return err
 	}
-}
+	defer d.leave()
 
-func (b *structBuilder) Key(k string) builder {
-	if b == nil {
-		return nobuilder
-	}
-	switch v := reflect.Indirect(b.val); v.Kind() {
-	case reflect.Struct:
-		t := v.Type()
-		// Case-insensitive field lookup.
-		k = strings.ToLower(k)
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			key := bencodeKey(field, nil)
-			if strings.ToLower(key) == k ||
-				strings.ToLower(field.Name) == k {
-				return &structBuilder{val: v.Field(i)}
+	depth := len(d.path)
+	var prevKey []byte
+	for n := 1; ; n++ {
+		end, err := d.atEnd()
+		if end || err != nil {
+			d.path = d.path[:depth]
+			return err
+		}
+		if err = d.checkEntries(n); err != nil {
+			return err
+		}
+		k, err := d.readKey()
+		if err != nil {
+			return err
+		}
+		if d.opts.strict {
+			if err = d.checkKeyOrder(n == 1, prevKey, k); err != nil {
+				return err
 			}
+			prevKey = append(prevKey[:0], k...)
 		}
-	case reflect.Map:
-		t := v.Type()
-		if t.Key() != reflect.TypeOf(k) {
-			break
+
+		if fields == nil {
+			key.SetString(string(k))
+			elem.SetZero()
+			d.path = append(d.path[:depth], pathElem{key: key.String(), index: -1})
+			if err = d.value(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+			continue
+		}
+		var fv reflect.Value
+		if f := fields.lookup(k, &d.fold); f != nil {
+			d.path = append(d.path[:depth], pathElem{key: f.key, index: -1})
+			if fv, err = fieldByIndexAlloc(v, f.index); err != nil {
+				d.saveError(err)
+			}
+		} else if d.opts.disallowUnknownFields {
+			d.path = append(d.path[:depth], pathElem{key: string(k), index: -1})
+			d.saveError(fmt.Errorf("bencode: unknown field %q", d.fieldPath()))
 		}
-		key := reflect.ValueOf(k)
-		elem := v.MapIndex(key)
-		if !elem.IsValid() {
-			v.SetMapIndex(key, reflect.Zero(t.Elem()))
-			elem = v.MapIndex(key)
+		if fv.IsValid() {
+			err = d.value(fv)
+		} else {
+			err = d.skip()
+		}
+		if err != nil {
+			return err
 		}
-		return &structBuilder{val: elem, map_: v, key: key}
 	}
-	return nobuilder
+}
+
+// A pathElem is a step on the way from the top-level value to the value
+// being unmarshalled: a dictionary key, or a list index if index >= 0.
+type pathElem struct {
+	key   string
+	index int
+}
+
+// fieldPath returns the path to the value being unmarshalled, such as
+// "info.files[3].length".
+func (d *decodeState) fieldPath() string {
+	var b strings.Builder
+	for _, p := range d.path {
+		if p.index >= 0 {
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(p.index))
+			b.WriteByte(']')
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(p.key)
+	}
+	return b.String()
+}
+
+// saveError records err to be returned once the value is unmarshalled,
+// unless an error has been recorded already.
+func (d *decodeState) saveError(err error) {
+	if d.savedErr == nil {
+		d.savedErr = err
+	}
+}
+
+// typeError records an UnmarshalTypeError for the value at valueOff,
+// described by value, which does not fit Go type t.
+func (d *decodeState) typeError(value string, t reflect.Type) {
+	if d.savedErr == nil {
+		d.savedErr = &UnmarshalTypeError{Value: value, Type: t, Offset: d.valueOff, Field: d.fieldPath()}
+	}
+}
+
+// An UnmarshalTypeError describes a bencode value that was not
+// appropriate for the Go value it was unmarshalled into.
+type UnmarshalTypeError struct {
+	Value  string       // description of the bencode value: "integer", "list", ...
+	Type   reflect.Type // type of the Go value it could not be assigned to
+	Offset int64        // offset of the bencode value in the input
+	Field  string       // path to the value, such as "info.files[3].length"
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Field == "" {
+		return "bencode: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+	}
+	return "bencode: cannot unmarshal " + e.Value + " at " + e.Field + " into Go value of type " + e.Type.String()
 }
 
 // Unmarshal reads and parses the bencode syntax data from r and fills in
 // an arbitrary struct or slice pointed at by val.
 // It uses the reflect package to assign to fields
-// and arrays embedded in val.  Well-formed data that does not fit
-// into the struct is discarded.
+// and arrays embedded in val.  Dictionary keys that match no struct field
+// are discarded, unless a Decoder is set to DisallowUnknownFields.
+//
+// If a bencode value is not appropriate for the Go value it is unmarshalled
+// into, such as a list for an int field, Unmarshal skips that value and
+// completes the unmarshalling as best it can. It then returns an
+// UnmarshalTypeError describing the earliest such value.
 //
 // For example, given these definitions:
 //
@@ -268,8 +597,11 @@ func (b *structBuilder) Key(k string) builder {
 // that the bencode field "address" was discarded.
 //
 // Because Unmarshal uses the reflect package, it can only
-// assign to upper case fields.  Unmarshal uses a case-insensitive
+// assign to upper case fields.  Unmarshal matches a bencode key to the
+// field with exactly that key, or failing that uses a case-insensitive
 // comparison to match bencode field names to struct field names.
+// Fields of embedded structs are promoted as described for Marshal, and
+// nil embedded pointers are allocated as needed.
 //
 // If you provide a tag string for a struct member, the tag string
 // will be used as the bencode dictionary key for that member.
@@ -279,306 +611,165 @@ func (b *structBuilder) Key(k string) builder {
 // syntax. The key for bencode values is bencode.
 //
 // To unmarshal a top-level bencode array, pass in a pointer to an empty
-// slice of the correct type.
+// slice of the correct type. A slice is resized to the length of the list;
+// array elements beyond it are zeroed. Nil pointers are allocated as
+// needed, and an empty interface receives the generic representation
+// described for Decode. Map values are decoded into fresh zero values.
+//
+// Bencode integers may be unmarshalled into integer and floating point
+// fields of any size. An integer that is out of range for the field, such
+// as 300 for an int8 or -1 for a uint32, leaves the field unchanged and is
+// reported as an UnmarshalTypeError. For compatibility, floating point
+// numbers are accepted as well, and truncated for integer fields.
+//
+// Bencode integers may be unmarshalled into bool fields: 0 is false and 1 is
+// true. Other integers are taken as true, or rejected with an
+// UnmarshalTypeError by a strict Decoder.
+// Number and big.Int fields hold integers of any size without loss.
+//
+// Bencode strings may be unmarshalled into string, []byte and byte array
+// fields, including named types such as "type Bitfield []byte". A byte
+// array must have exactly the length of the string.
+//
+// If a value implements Unmarshaler, or is addressable and its pointer
+// implements Unmarshaler, Unmarshal calls its UnmarshalBencode method with
+// the encoding of the corresponding bencode value, allocating a new value
+// for a nil pointer first.
 //
 func Unmarshal(r io.Reader, val interface{}) (err error) {
-	// If e represents a value, the answer won't get back to the
-	// caller.  Make sure it's a pointer.
-	if reflect.TypeOf(val).Kind() != reflect.Ptr {
-		err = errors.New("Attempt to unmarshal into a non-pointer")
-		return
-	}
-	err = unmarshalValue(r, reflect.Indirect(reflect.ValueOf(val)))
-	return
+	return DefaultLimits.Unmarshal(r, val)
 }
 
-func unmarshalValue(r io.Reader, v reflect.Value) (err error) {
-	var b *structBuilder
-
-	// XXX: Decide if the extra codnitions are needed. Affect map?
-	if ptr := v; ptr.Kind() == reflect.Ptr {
-		if slice := ptr.Elem(); slice.Kind() == reflect.Slice || slice.Kind() == reflect.Int || slice.Kind() == reflect.String {
-			b = &structBuilder{val: slice}
-		}
-	}
-
-	if b == nil {
-		b = &structBuilder{val: v}
-	}
-
-	err = parse(r, b)
-	return
-}
-
-type MarshalError struct {
-	T reflect.Type
-}
-
-func (e *MarshalError) Error() string {
-	return "bencode cannot encode value of type " + e.T.String()
-}
-
-func writeArrayOrSlice(w io.Writer, val reflect.Value) (err error) {
-	_, err = fmt.Fprint(w, "l")
-	if err != nil {
-		return
-	}
-	for i := 0; i < val.Len(); i++ {
-		if err := writeValue(w, val.Index(i)); err != nil {
-			return err
-		}
-	}
-
-	_, err = fmt.Fprint(w, "e")
+// Unmarshal is like the package level Unmarshal, but applies the limits l
+// rather than DefaultLimits.
+func (l Limits) Unmarshal(r io.Reader, val interface{}) (err error) {
+	v, err := unmarshalTarget(val)
 	if err != nil {
 		return
 	}
-	return nil
-}
-
-type stringValue struct {
-	key       string
-	value     reflect.Value
-	omitEmpty bool
+	err = unmarshalValue(r, v, &decodeOptions{limits: l})
+	return
 }
 
-type stringValueArray []stringValue
-
-// Satisfy sort.Interface
-
-func (a stringValueArray) Len() int { return len(a) }
-
-func (a stringValueArray) Less(i, j int) bool { return a[i].key < a[j].key }
-
-func (a stringValueArray) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
-
-func writeSVList(w io.Writer, svList stringValueArray) (err error) {
-	sort.Sort(svList)
-
-	for _, sv := range svList {
-		if sv.isValueNil() {
-			continue // Skip null values
-		}
-		s := sv.key
-		_, err = fmt.Fprintf(w, "%d:%s", len(s), s)
-		if err != nil {
-			return
-		}
-
-		if err = writeValue(w, sv.value); err != nil {
-			return
-		}
-	}
-	return
+// UnmarshalBytes is like Unmarshal, but parses the value held in data
+// directly, without the copy through a bufio.Reader. It is an error for
+// data to hold anything after the value.
+func UnmarshalBytes(data []byte, val interface{}) error {
+	return DefaultLimits.UnmarshalBytes(data, val)
 }
 
-func writeMap(w io.Writer, val reflect.Value) (err error) {
-	key := val.Type().Key()
-	if key.Kind() != reflect.String {
-		return &MarshalError{val.Type()}
-	}
-	_, err = fmt.Fprint(w, "d")
+// UnmarshalBytes is like the package level UnmarshalBytes, but applies the
+// limits l rather than DefaultLimits.
+func (l Limits) UnmarshalBytes(data []byte, val interface{}) error {
+	v, err := unmarshalTarget(val)
 	if err != nil {
-		return
+		return err
 	}
-
-	keys := val.MapKeys()
-
-	// Sort keys
-
-	svList := make(stringValueArray, len(keys))
-	for i, key := range keys {
-		svList[i].key = key.String()
-		svList[i].value = val.MapIndex(key)
+	d := decodeState{data: data, opts: &decodeOptions{limits: l}}
+	if err := d.begin(); err != nil {
+		return err
 	}
-
-	err = writeSVList(w, svList)
-	if err != nil {
-		return
+	if err := d.value(v); err != nil {
+		return err
 	}
-
-	_, err = fmt.Fprint(w, "e")
-	if err != nil {
-		return
+	if err := d.checkEnd(); err != nil {
+		return err
 	}
-	return
+	return d.savedErr
 }
 
-func bencodeKey(field reflect.StructField, sv *stringValue) (key string) {
-	key = field.Name
-	tag := field.Tag
-	if len(tag) > 0 {
-		// Backwards compatability
-		// If there's a bencode key/value entry in the tag, use it.
-		var tagOpt tagOptions
-		key, tagOpt = parseTag(tag.Get("bencode"))
-		if len(key) == 0 {
-			key = tag.Get("bencode")
-			if len(key) == 0 && !strings.Contains(string(tag), ":") {
-				// If there is no ":" in the tag, assume it is an old-style tag.
-				key = string(tag)
-			} else {
-				key = field.Name
-			}
-		}
-		if sv != nil && tagOpt.Contains("omitempty") {
-			sv.omitEmpty = true
-		}
+// unmarshalTarget returns the value that val points to.
+func unmarshalTarget(val interface{}) (reflect.Value, error) {
+	// If val represents a value, the answer won't get back to the
+	// caller.  Make sure it's a pointer.
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Ptr {
+		return reflect.Value{}, errors.New("Attempt to unmarshal into a non-pointer")
 	}
-	if sv != nil {
-		sv.key = key
+	if v.IsNil() {
+		return reflect.Value{}, errors.New("Attempt to unmarshal into a nil pointer")
 	}
-	return
+	return v.Elem(), nil
 }
 
-// tagOptions is the string following a comma in a struct field's "bencode"
-// tag, or the empty string. It does not include the leading comma.
-type tagOptions string
-
-// parseTag splits a struct field's bencode tag into its name and
-// comma-separated options.
-func parseTag(tag string) (string, tagOptions) {
-	if idx := strings.Index(tag, ","); idx != -1 {
-		return tag[:idx], tagOptions(tag[idx+1:])
-	}
-	return tag, tagOptions("")
+func unmarshalValue(r io.Reader, v reflect.Value, opts *decodeOptions) error {
+	d := decodeState{opts: opts}
+	vr := d.readFrom(r)
+	return vr.done(d.unmarshal(v))
 }
 
-// Contains reports whether a comma-separated list of options
-// contains a particular substr flag. substr must be surrounded by a
-// string boundary or commas.
-func (o tagOptions) Contains(optionName string) bool {
-	if len(o) == 0 {
-		return false
+// unmarshal stores the next value in v. It returns io.EOF if there is no
+// input left, and io.ErrUnexpectedEOF if the input ends inside the value.
+func (d *decodeState) unmarshal(v reflect.Value) error {
+	if err := d.begin(); err != nil {
+		return err
 	}
-	s := string(o)
-	for s != "" {
-		var next string
-		i := strings.Index(s, ",")
-		if i >= 0 {
-			s, next = s[:i], s[i+1:]
-		}
-		if s == optionName {
-			return true
-		}
-		s = next
+	if err := d.value(v); err != nil {
+		return err
 	}
-	return false
+	return d.savedErr
 }
 
-func writeStruct(w io.Writer, val reflect.Value) (err error) {
-	_, err = fmt.Fprint(w, "d")
-	if err != nil {
-		return
-	}
-
-	typ := val.Type()
-
-	numFields := val.NumField()
-	svList := make(stringValueArray, numFields)
+// Marshaler is the interface implemented by types that can marshal
+// themselves into bencode. MarshalBencode must return the encoding of
+// exactly one well-formed value.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
 
-	for i := 0; i < numFields; i++ {
-		field := typ.Field(i)
-		bencodeKey(field, &svList[i])
-		// The tag `bencode:"-"` should mean that this field must be ignored
-		// See https://golang.org/pkg/encoding/json/#Marshal or https://golang.org/pkg/encoding/xml/#Marshal
-		// We set a zero value so that it is ignored by the writeSVList() function
-		if svList[i].key == "-" {
-			svList[i].value = reflect.Value{}
-		} else {
-			svList[i].value = val.Field(i)
-		}
-	}
+// Unmarshaler is the interface implemented by types that can unmarshal a
+// bencode description of themselves. The input is the encoding of exactly
+// one well-formed value. UnmarshalBencode must copy the data if it wishes
+// to retain it after returning.
+type Unmarshaler interface {
+	UnmarshalBencode([]byte) error
+}
 
-	err = writeSVList(w, svList)
-	if err != nil {
-		return
-	}
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
 
-	_, err = fmt.Fprint(w, "e")
-	if err != nil {
-		return
-	}
-	return
+// A MarshalerError is returned by Marshal when a MarshalBencode method
+// fails or returns something other than a single well-formed value.
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
 }
 
-func writeValue(w io.Writer, val reflect.Value) (err error) {
-	if !val.IsValid() {
-		err = errors.New("Can't write null value")
-		return
-	}
-
-	switch v := val; v.Kind() {
-	case reflect.String:
-		s := v.String()
-		_, err = fmt.Fprintf(w, "%d:%s", len(s), s)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		_, err = fmt.Fprintf(w, "i%de", v.Int())
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		_, err = fmt.Fprintf(w, "i%de", v.Uint())
-	case reflect.Array:
-		err = writeArrayOrSlice(w, v)
-	case reflect.Slice:
-		switch val.Type().String() {
-		case "[]uint8":
-			// special case as byte-string
-			s := string(v.Bytes())
-			_, err = fmt.Fprintf(w, "%d:%s", len(s), s)
-		default:
-			err = writeArrayOrSlice(w, v)
-		}
-	case reflect.Map:
-		err = writeMap(w, v)
-	case reflect.Struct:
-		err = writeStruct(w, v)
-	case reflect.Interface:
-		err = writeValue(w, v.Elem())
-	default:
-		err = &MarshalError{val.Type()}
-	}
-	return
+func (e *MarshalerError) Error() string {
+	return "bencode: error calling MarshalBencode for type " + e.Type.String() + ": " + e.Err.Error()
 }
 
-func isEmptyValue(v reflect.Value) bool {
-	switch v.Kind() {
-	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
-		return v.Len() == 0
-	case reflect.Bool:
-		return !v.Bool()
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return v.Int() == 0
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		return v.Uint() == 0
-	case reflect.Float32, reflect.Float64:
-		return v.Float() == 0
-	case reflect.Interface, reflect.Ptr:
-		return v.IsNil()
-	}
-	return false
+func (e *MarshalerError) Unwrap() error { return e.Err }
+
+type MarshalError struct {
+	T reflect.Type
 }
 
-func (sv stringValue) isValueNil() bool {
-	if !sv.value.IsValid() || (sv.omitEmpty && isEmptyValue(sv.value)) {
-		return true
-	}
-	switch v := sv.value; v.Kind() {
-	case reflect.Interface:
-		return !v.Elem().IsValid()
-	}
-	return false
+func (e *MarshalError) Error() string {
+	return "bencode cannot encode value of type " + e.T.String()
 }
 
 // Marshal writes the bencode encoding of val to w.
 //
 // Marshal traverses the value v recursively.
+// If an encountered value implements the Marshaler interface, either
+// directly or through its pointer, Marshal calls its MarshalBencode method
+// and writes the result, which must be a single well-formed bencode value.
 //
 // Marshal uses the following type-dependent encodings:
 //
-// Floating point, integer, and Number values encode as bencode numbers.
+// Integer values encode as bencode numbers, as do Number and big.Int
+// values. Boolean values encode as the integers 0 and 1. Floating point
+// values cannot be encoded by Marshal; an Encoder can be configured to
+// accept them with SetFloatPolicy.
 //
 // String values encode as bencode strings.
 //
-// Array and slice values encode as bencode arrays.
+// Array and slice values encode as bencode arrays, except that byte
+// slices and byte arrays, such as []byte and [20]byte, encode as bencode
+// strings.
 //
 // Struct values encode as bencode maps. Each exported struct field
 // becomes a member of the object.
@@ -592,21 +783,75 @@ func (sv stringValue) isValueNil() bool {
 //   // Field appears in bencode as key "myName".
 //   Field int "myName"
 //
-// Anonymous struct fields are ignored.
+// Unexported struct fields and fields tagged "-" are ignored. The fields of
+// an anonymous struct field, or of an anonymous pointer to a struct, are
+// promoted into the enclosing dictionary, following the rules of
+// encoding/json: if several fields share a key, the least nested one wins,
+// a tagged field beats an untagged one at the same depth, and otherwise
+// all of them are left out. An anonymous struct field with a tag is
+// encoded as a nested dictionary under that key.
 //
 // Map values encode as bencode objects.
 // The map's key type must be string; the object keys are used directly
 // as map keys.
 //
-// Boolean, Pointer, Interface, Channel, complex, and function values cannot
+// Pointer values encode as the value pointed to. Nil pointers and nil
+// interface values are left out of dictionaries, and cannot be encoded
+// elsewhere.
+//
+// Channel, complex, and function values cannot
 // be encoded in bencode.
 // Attempting to encode such a value causes Marshal to return
 // a MarshalError.
 //
 // Bencode cannot represent cyclic data structures and Marshal does not
 // handle them.  Passing cyclic structures to Marshal will result in
-// an infinite recursion.
+// an infinite recursion. An Encoder with cycle detection enabled
+// reports an error instead.
 //
+// The encoding is built in memory and written to w with a single call
+// to Write. Nothing is written if an error occurs.
 func Marshal(w io.Writer, val interface{}) error {
-	return writeValue(w, reflect.ValueOf(val))
+	e := newEncodeState()
+	defer freeEncodeState(e)
+	if err := e.writeValue(reflect.ValueOf(val)); err != nil {
+		return err
+	}
+	_, err := w.Write(e.buf)
+	return err
+}
+
+// AppendMarshal appends the bencode encoding of val to dst and returns the
+// extended buffer, following the same rules as Marshal. If an error
+// occurs, AppendMarshal returns dst and the error.
+//
+// AppendMarshal makes no allocations of its own when val is a pointer to
+// a struct of strings, byte slices, integers and nested structs and dst
+// has room for the encoding, so a reply can be encoded into a reused
+// packet buffer:
+//
+//	pkt, err = bencode.AppendMarshal(pkt[:0], &reply)
+func AppendMarshal(dst []byte, val interface{}) ([]byte, error) {
+	e := newEncodeState()
+	defer freeEncodeState(e)
+	own := e.buf
+	e.buf = dst
+	err := e.writeValue(reflect.ValueOf(val))
+	out := e.buf
+	e.buf = own
+	if err != nil {
+		return dst, err
+	}
+	return out, nil
+}
+
+// MarshalBytes returns the bencode encoding of val, following the same
+// rules as Marshal.
+func MarshalBytes(val interface{}) ([]byte, error) {
+	e := newEncodeState()
+	defer freeEncodeState(e)
+	if err := e.writeValue(reflect.ValueOf(val)); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(e.buf), nil
 }
@@ -0,0 +1,226 @@
+package bencode
+
+import (
+	"errors"
+	"strconv"
+)
+
+// A TokenKind identifies the kind of a Token.
+type TokenKind uint8
+
+const (
+	_         TokenKind = iota
+	Int                 // an integer
+	Bytes               // a string, which may be a dictionary key
+	ListStart           // the 'l' starting a list
+	DictStart           // the 'd' starting a dictionary
+	End                 // the 'e' ending a list or dictionary
+)
+
+var tokenKindNames = [...]string{
+	Int:       "Int",
+	Bytes:     "Bytes",
+	ListStart: "ListStart",
+	DictStart: "DictStart",
+	End:       "End",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) && tokenKindNames[k] != "" {
+		return tokenKindNames[k]
+	}
+	return "TokenKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// A Token is one element of a bencode stream, as returned by
+// Decoder.Token.
+type Token struct {
+	Kind TokenKind
+
+	// Value holds the text of an Int or the contents of a Bytes token.
+	// It is only valid until the next call to the Decoder.
+	Value []byte
+}
+
+// Int64 returns the value of an Int token.
+func (t Token) Int64() (int64, error) {
+	if t.Kind != Int {
+		return 0, errors.New("bencode: Int64 of " + t.Kind.String() + " token")
+	}
+	if i, ok := parseInteger(t.Value); ok {
+		return i, nil
+	}
+	return strconv.ParseInt(string(t.Value), 10, 64)
+}
+
+// Number returns the text of an Int token as a Number, or "" for other
+// tokens.
+func (t Token) Number() Number {
+	if t.Kind != Int {
+		return ""
+	}
+	return Number(t.Value)
+}
+
+// A tokenFrame is a list or dictionary whose start has been returned by
+// Decoder.Token and whose end has not.
+type tokenFrame struct {
+	dict    bool
+	n       int    // values read so far, counting dictionary keys
+	prevKey []byte // the last dictionary key, in strict mode
+}
+
+var errDecodeKey = errors.New("bencode: cannot decode a dictionary key; read it with Token")
+
+// Token returns the next token in the input: an Int or Bytes for a
+// string, ListStart or DictStart, and End at the end of each list and
+// dictionary. The entries of a dictionary are returned as a Bytes token
+// for the key followed by the tokens of the value. Token checks the input
+// as Decode does, including the options and limits set on the Decoder,
+// and returns io.EOF at the end of the input.
+//
+// Token does not build anything, so a large stream can be walked in
+// constant memory. Once the key of a wanted dictionary entry has been
+// read, the value can be decoded with Decode, DecodeInto or DecodeValue,
+// and unwanted values can be passed over with Skip. Between the tokens of
+// a list or dictionary, More reports whether it has another value.
+func (d *Decoder) Token() (Token, error) {
+	return d.ds.token(false)
+}
+
+// Skip reads the next token, and if it starts a list or dictionary, the
+// rest of it up to its End. The skipped input is checked in the same way
+// as by Token.
+func (d *Decoder) Skip() error {
+	_, err := d.ds.token(true)
+	return err
+}
+
+func (d *decodeState) token(skip bool) (Token, error) {
+	if len(d.tokens) == 0 {
+		if err := d.begin(); err != nil {
+			return Token{}, err
+		}
+	} else {
+		top := &d.tokens[len(d.tokens)-1]
+		key := top.dict && top.n%2 == 0
+		end, err := d.nextInFrame()
+		if err != nil {
+			return Token{}, err
+		}
+		if end {
+			d.leave()
+			d.tokens = d.tokens[:len(d.tokens)-1]
+			return Token{Kind: End}, nil
+		}
+		if key {
+			return d.keyToken(top)
+		}
+	}
+
+	c, err := d.readByte()
+	if err != nil {
+		return Token{}, err
+	}
+	switch {
+	case c == 'i':
+		buf, err := d.readInteger()
+		if err != nil {
+			return Token{}, err
+		}
+		if !isInteger(buf) {
+			return Token{}, d.tokenError(badInteger(buf), buf)
+		}
+		return Token{Kind: Int, Value: buf}, nil
+	case c == 'l' && skip:
+		return Token{Kind: ListStart}, d.skipList()
+	case c == 'd' && skip:
+		return Token{Kind: DictStart}, d.skipDict()
+	case c == 'l' || c == 'd':
+		if err := d.enter(); err != nil {
+			return Token{}, err
+		}
+		d.pushFrame(c == 'd')
+		if c == 'l' {
+			return Token{Kind: ListStart}, nil
+		}
+		return Token{Kind: DictStart}, nil
+	case c >= '0' && c <= '9':
+		if err = d.unreadByte(); err != nil {
+			return Token{}, err
+		}
+		buf, err := d.readString()
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Kind: Bytes, Value: buf}, nil
+	}
+	return Token{}, d.unexpected(c, expectedValue)
+}
+
+// nextInFrame counts the next value of the list or dictionary at the top
+// of the token stack, checking its limits. At a position where the list or
+// dictionary may end, it reports whether it does, consuming the 'e'.
+func (d *decodeState) nextInFrame() (end bool, err error) {
+	top := &d.tokens[len(d.tokens)-1]
+	if !top.dict || top.n%2 == 0 {
+		if end, err = d.atEnd(); end || err != nil {
+			return end, err
+		}
+		entries := top.n + 1
+		if top.dict {
+			entries = top.n/2 + 1
+		}
+		if err = d.checkEntries(entries); err != nil {
+			return false, err
+		}
+	}
+	top.n++
+	return false, nil
+}
+
+// keyToken reads the next key of the dictionary top.
+func (d *decodeState) keyToken(top *tokenFrame) (Token, error) {
+	key, err := d.readKey()
+	if err != nil {
+		return Token{}, err
+	}
+	if d.opts.strict {
+		if err = d.checkKeyOrder(top.n == 1, top.prevKey, key); err != nil {
+			return Token{}, err
+		}
+		top.prevKey = append(top.prevKey[:0], key...)
+	}
+	return Token{Kind: Bytes, Value: key}, nil
+}
+
+// pushFrame records the start of a list or dictionary read by Token,
+// reusing the key buffer of a previous frame.
+func (d *decodeState) pushFrame(dict bool) {
+	if n := len(d.tokens); n < cap(d.tokens) {
+		d.tokens = d.tokens[:n+1]
+		d.tokens[n].dict = dict
+		d.tokens[n].n = 0
+		return
+	}
+	d.tokens = append(d.tokens, tokenFrame{dict: dict})
+}
+
+// beginInFrame prepares to decode a value nested in the list or dictionary
+// at the top of the token stack.
+func (d *decodeState) beginInFrame() error {
+	top := &d.tokens[len(d.tokens)-1]
+	if top.dict && top.n%2 == 0 {
+		return errDecodeKey
+	}
+	end, err := d.nextInFrame()
+	if err != nil {
+		return err
+	}
+	if end {
+		d.leave()
+		d.tokens = d.tokens[:len(d.tokens)-1]
+		return d.unexpected('e', expectedValue)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// tokenString formats the tokens of a Decoder compactly, such as
+// "d 4:name i1 e".
+func tokenString(d *Decoder) (string, error) {
+	var parts []string
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return strings.Join(parts, " "), nil
+		}
+		if err != nil {
+			return strings.Join(parts, " "), err
+		}
+		switch tok.Kind {
+		case Int:
+			parts = append(parts, "i"+string(tok.Value))
+		case Bytes:
+			parts = append(parts, fmt.Sprintf("%d:%s", len(tok.Value), tok.Value))
+		case ListStart:
+			parts = append(parts, "l")
+		case DictStart:
+			parts = append(parts, "d")
+		case End:
+			parts = append(parts, "e")
+		}
+	}
+}
+
+func TestDecoderToken(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"i42e", "i42"},
+		{"i-0e3:abc", "i-0 3:abc"},
+		{"le", "l e"},
+		{"d4:infod4:name1:a6:lengthi3eee", "d 4:info d 4:name 1:a 6:length i3 e e"},
+		{"ll1:aeli1ei2eeed0:dee", "l l 1:a e l i1 i2 e e d 0: d e e"},
+	}
+	for _, tt := range tests {
+		for _, d := range []*Decoder{NewDecoder(strings.NewReader(tt.in)), NewBytesDecoder([]byte(tt.in))} {
+			got, err := tokenString(d)
+			if err != nil || got != tt.want {
+				t.Errorf("tokens of %q = %q, %v, want %q", tt.in, got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestDecoderTokenPick(t *testing.T) {
+	// Walk a resume file, decoding one field of each entry and skipping
+	// the rest.
+	in := "d" +
+		"5:a.iso" + "d6:piecesli1ei2ee4:sizei10e5:peersd1:xi1eee" +
+		"5:b.iso" + "d4:sizei20e6:piecesl3:abcee" + "e"
+	for _, d := range []*Decoder{NewDecoder(strings.NewReader(in)), NewBytesDecoder([]byte(in))} {
+		sizes := map[string]int64{}
+		if tok, err := d.Token(); err != nil || tok.Kind != DictStart {
+			t.Fatalf("first token = %v, %v", tok, err)
+		}
+		for d.More() {
+			tok, err := d.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := string(tok.Value)
+			if tok, err := d.Token(); err != nil || tok.Kind != DictStart {
+				t.Fatalf("token after %q = %v, %v", name, tok, err)
+			}
+			for d.More() {
+				tok, err := d.Token()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(tok.Value) != "size" {
+					if err := d.Skip(); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				var size int64
+				if err := d.DecodeInto(&size); err != nil {
+					t.Fatal(err)
+				}
+				sizes[name] = size
+			}
+			if tok, err := d.Token(); err != nil || tok.Kind != End {
+				t.Fatalf("token at end of %q = %v, %v", name, tok, err)
+			}
+		}
+		if tok, err := d.Token(); err != nil || tok.Kind != End {
+			t.Fatalf("last token = %v, %v", tok, err)
+		}
+		if _, err := d.Token(); err != io.EOF {
+			t.Fatalf("Token at end of input = %v, want io.EOF", err)
+		}
+		if sizes["a.iso"] != 10 || sizes["b.iso"] != 20 || len(sizes) != 2 {
+			t.Errorf("sizes = %v", sizes)
+		}
+	}
+}
+
+func TestDecoderTokenMixed(t *testing.T) {
+	d := NewDecoder(strings.NewReader("li1e1:ad1:bi2eeli3eeei4e"))
+	d.Token()
+	if v, err := d.Decode(); err != nil || v != int64(1) {
+		t.Errorf("Decode = %v, %v", v, err)
+	}
+	if err := d.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.DecodeValue(); err != nil || v.Get("b").Number() != "2" {
+		t.Errorf("DecodeValue = %v, %v", v, err)
+	}
+	var l []int
+	if err := d.DecodeInto(&l); err != nil || len(l) != 1 || l[0] != 3 {
+		t.Errorf("DecodeInto = %v, %v", l, err)
+	}
+	if d.More() {
+		t.Error("More at the end of a list")
+	}
+	if _, err := d.Decode(); err == nil {
+		t.Error("Decode at the end of a list succeeded")
+	}
+	// The failed Decode consumed the End of the list.
+	if v, err := d.Decode(); err != nil || v != int64(4) {
+		t.Errorf("Decode after the list = %v, %v", v, err)
+	}
+
+	d = NewDecoder(strings.NewReader("d1:ai1ee"))
+	d.Token()
+	if _, err := d.Decode(); err != errDecodeKey {
+		t.Errorf("Decode at a key = %v, want %v", err, errDecodeKey)
+	}
+	if err := d.Skip(); err != nil {
+		t.Errorf("Skip at a key = %v", err)
+	}
+	if tok, err := d.Token(); err != nil || tok.Kind != Int {
+		t.Errorf("Token after skipping a key = %v, %v", tok, err)
+	}
+
+	tok := Token{Kind: Int, Value: []byte("-12345678901234567890")}
+	if _, err := tok.Int64(); err == nil {
+		t.Error("Int64 of an overflowing token succeeded")
+	}
+	if tok.Number() != "-12345678901234567890" {
+		t.Errorf("Number() = %q", tok.Number())
+	}
+	if _, err := (Token{Kind: Bytes}).Int64(); err == nil {
+		t.Error("Int64 of a Bytes token succeeded")
+	}
+}
+
+func TestDecoderTokenErrors(t *testing.T) {
+	tests := []struct {
+		in     string
+		strict bool
+		limits Limits
+		err    error
+	}{
+		{"d1:ae", false, DefaultLimits, &SyntaxError{4, 'e', expectedValue}},
+		{"di1ee", false, DefaultLimits, &SyntaxError{1, 'i', expectedKey}},
+		{"li1.5ee", false, DefaultLimits, &SyntaxError{3, '.', "digit or 'e'"}},
+		{"d1:bi1e1:ai2ee", true, DefaultLimits, &SyntaxError{9, 'a', `key sorting after "b"`}},
+		{"d0:i1e0:i2ee", true, DefaultLimits, &SyntaxError{7, ':', `key sorting after ""`}},
+		{"d1:ai1e1:bd1:bi1e1:ai2eee", true, DefaultLimits, &SyntaxError{19, 'a', `key sorting after "b"`}},
+		{"lllleeee", false, Limits{MaxDepth: 3}, &LimitError{"MaxDepth", 3}},
+		{"li1ei2ei3ee", false, Limits{MaxEntries: 2}, &LimitError{"MaxEntries", 2}},
+		{"l10:aaaaaaaaaae", false, Limits{MaxStringLen: 5}, &LimitError{"MaxStringLen", 5}},
+		{"li1ei2ei3ei4ee", false, Limits{MaxBytes: 10}, &LimitError{"MaxBytes", 10}},
+		{"li1e", false, DefaultLimits, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		d := NewBytesDecoder([]byte(tt.in))
+		d.SetStrict(tt.strict)
+		d.SetLimits(tt.limits)
+		_, err := tokenString(d)
+		var se *SyntaxError
+		var le *LimitError
+		switch want := tt.err.(type) {
+		case *SyntaxError:
+			if !errors.As(err, &se) || *se != *want {
+				t.Errorf("tokens of %q: %v, want %v", tt.in, err, want)
+			}
+		case *LimitError:
+			if !errors.As(err, &le) || *le != *want {
+				t.Errorf("tokens of %q: %v, want %v", tt.in, err, want)
+			}
+		default:
+			if err != want {
+				t.Errorf("tokens of %q: %v, want %v", tt.in, err, want)
+			}
+		}
+	}
+
+	// Skip applies the same checks.
+	d := NewBytesDecoder([]byte("ld1:bi1e1:ai2eee"))
+	d.SetStrict(true)
+	d.Token()
+	if err := d.Skip(); err == nil {
+		t.Error("strict Skip accepted unsorted keys")
+	}
+}
@@ -0,0 +1,427 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// A Kind is the kind of bencode value held by a Value.
+type Kind uint8
+
+const (
+	KindInvalid Kind = iota // the zero Value
+	KindInteger
+	KindString
+	KindList
+	KindDict
+)
+
+var kindNames = [...]string{
+	KindInvalid: "invalid",
+	KindInteger: "integer",
+	KindString:  "string",
+	KindList:    "list",
+	KindDict:    "dictionary",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "kind " + strconv.Itoa(int(k))
+}
+
+// A Value is a decoded bencode value that remembers exactly how it was
+// written: the order of dictionary keys, duplicate keys, and integers and
+// string lengths with leading zeros. Encoding a Value decoded by
+// DecodeValue reproduces its input byte for byte, so a torrent can be
+// inspected and edited without changing the hashes of the parts that were
+// left alone.
+//
+// Values are built by DecodeValue and by the functions IntValue,
+// StringValue, BytesValue, ListValue and DictValue. The zero Value is
+// invalid; it is what the accessors return for missing values, and it is
+// left out of dictionaries when encoded.
+//
+// Copies of a Value share memory, but the methods that modify a Value
+// copy what they change, so a Value can be passed and stored like an
+// int. The strings of a decoded Value share the buffer they were decoded
+// from, and must not be modified.
+type Value struct {
+	kind Kind
+	data []byte // the text of an integer or the contents of a string
+	size []byte // the length of a string as written, if not canonical
+	list []Value
+	dict []DictEntry
+}
+
+// A DictEntry is a key and value of a dictionary Value.
+type DictEntry struct {
+	Key   string
+	Value Value
+
+	size []byte // the length of Key as written, if not canonical
+}
+
+var valueType = reflect.TypeOf(Value{})
+
+// IntValue returns an integer Value.
+func IntValue(i int64) Value {
+	return Value{kind: KindInteger, data: strconv.AppendInt(nil, i, 10)}
+}
+
+// StringValue returns a string Value holding s.
+func StringValue(s string) Value {
+	return Value{kind: KindString, data: []byte(s)}
+}
+
+// BytesValue returns a string Value holding b, which it does not copy.
+func BytesValue(b []byte) Value {
+	if b == nil {
+		b = []byte{}
+	}
+	return Value{kind: KindString, data: b}
+}
+
+// ListValue returns a list Value holding items.
+func ListValue(items ...Value) Value {
+	if items == nil {
+		items = []Value{}
+	}
+	return Value{kind: KindList, list: items}
+}
+
+// DictValue returns a dictionary Value holding entries in the order given.
+func DictValue(entries ...DictEntry) Value {
+	if entries == nil {
+		entries = []DictEntry{}
+	}
+	return Value{kind: KindDict, dict: entries}
+}
+
+// Kind returns the kind of v.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Number returns the text of an integer, or "" if v is not an integer.
+func (v Value) Number() Number {
+	if v.kind != KindInteger {
+		return ""
+	}
+	return Number(v.data)
+}
+
+// Int64 returns the value of an integer.
+func (v Value) Int64() (int64, error) {
+	if v.kind != KindInteger {
+		return 0, errors.New("bencode: Int64 of " + v.kind.String() + " Value")
+	}
+	return strconv.ParseInt(string(v.data), 10, 64)
+}
+
+// Bytes returns the contents of a string, or nil if v is not a string.
+// The result must not be modified.
+func (v Value) Bytes() []byte {
+	if v.kind != KindString {
+		return nil
+	}
+	return v.data
+}
+
+// String returns the contents of a string. For other kinds of Value it
+// returns a description such as "<list Value>".
+func (v Value) String() string {
+	if v.kind != KindString {
+		return "<" + v.kind.String() + " Value>"
+	}
+	return string(v.data)
+}
+
+// Len returns the number of items in a list, of entries in a dictionary,
+// or of bytes in a string, and 0 for other kinds of Value.
+func (v Value) Len() int {
+	switch v.kind {
+	case KindString:
+		return len(v.data)
+	case KindList:
+		return len(v.list)
+	case KindDict:
+		return len(v.dict)
+	}
+	return 0
+}
+
+// Index returns item i of a list, or the zero Value if v is not a list or
+// i is out of range.
+func (v Value) Index(i int) Value {
+	if v.kind != KindList || i < 0 || i >= len(v.list) {
+		return Value{}
+	}
+	return v.list[i]
+}
+
+// Get returns the value for key in a dictionary, or the zero Value if v is
+// not a dictionary or has no such key. If the key occurs more than once,
+// the last entry wins, as it does for Decode and Unmarshal.
+func (v Value) Get(key string) Value {
+	for i := len(v.dict) - 1; i >= 0; i-- {
+		if v.dict[i].Key == key {
+			return v.dict[i].Value
+		}
+	}
+	return Value{}
+}
+
+// Entries returns the entries of a dictionary in their order in the
+// encoding, or nil if v is not a dictionary. The result must not be
+// modified.
+func (v Value) Entries() []DictEntry {
+	return v.dict
+}
+
+// Set sets the value for key in a dictionary. Every entry with the key
+// takes the value and keeps its position. If there is none, a new entry is inserted
+// before the first key that sorts after it, which keeps a canonical
+// dictionary canonical. Set panics if v is not a dictionary.
+func (v *Value) Set(key string, val Value) {
+	if v.kind != KindDict {
+		panic("bencode: Set on " + v.kind.String() + " Value")
+	}
+	dict := make([]DictEntry, len(v.dict), len(v.dict)+1)
+	copy(dict, v.dict)
+	found := false
+	for i := range dict {
+		if dict[i].Key == key {
+			dict[i].Value = val
+			found = true
+		}
+	}
+	if !found {
+		i := 0
+		for i < len(dict) && dict[i].Key <= key {
+			i++
+		}
+		dict = slices.Insert(dict, i, DictEntry{Key: key, Value: val})
+	}
+	v.dict = dict
+}
+
+// Delete removes every entry with key from a dictionary. Delete panics if
+// v is not a dictionary.
+func (v *Value) Delete(key string) {
+	if v.kind != KindDict {
+		panic("bencode: Delete on " + v.kind.String() + " Value")
+	}
+	dict := make([]DictEntry, 0, len(v.dict))
+	for _, e := range v.dict {
+		if e.Key != key {
+			dict = append(dict, e)
+		}
+	}
+	v.dict = dict
+}
+
+// Append adds items to the end of a list. Append panics if v is not a
+// list.
+func (v *Value) Append(items ...Value) {
+	if v.kind != KindList {
+		panic("bencode: Append on " + v.kind.String() + " Value")
+	}
+	v.list = append(v.list[:len(v.list):len(v.list)], items...)
+}
+
+// Encode returns the encoding of v. Parts of v that were decoded by
+// DecodeValue and not modified since are written exactly as they were
+// read. Invalid values are left out of dictionaries and are an error
+// elsewhere.
+func (v Value) Encode() ([]byte, error) {
+	return v.appendTo(nil)
+}
+
+func (v Value) appendTo(buf []byte) ([]byte, error) {
+	var err error
+	switch v.kind {
+	case KindInteger:
+		buf = append(buf, 'i')
+		buf = append(buf, v.data...)
+		buf = append(buf, 'e')
+	case KindString:
+		buf = appendString(buf, v.size, v.data)
+	case KindList:
+		buf = append(buf, 'l')
+		for _, item := range v.list {
+			if buf, err = item.appendTo(buf); err != nil {
+				return nil, err
+			}
+		}
+		buf = append(buf, 'e')
+	case KindDict:
+		buf = append(buf, 'd')
+		for _, e := range v.dict {
+			if e.Value.kind == KindInvalid {
+				continue
+			}
+			buf = appendString(buf, e.size, e.Key)
+			if buf, err = e.Value.appendTo(buf); err != nil {
+				return nil, err
+			}
+		}
+		buf = append(buf, 'e')
+	default:
+		return nil, errors.New("bencode: cannot encode invalid Value")
+	}
+	return buf, nil
+}
+
+// appendString appends a string with contents s, writing its length as
+// size if that is set.
+func appendString[S string | []byte](buf, size []byte, s S) []byte {
+	if size != nil {
+		buf = append(buf, size...)
+	} else {
+		buf = strconv.AppendInt(buf, int64(len(s)), 10)
+	}
+	buf = append(buf, ':')
+	return append(buf, s...)
+}
+
+// MarshalBencode returns the encoding of v.
+func (v Value) MarshalBencode() ([]byte, error) {
+	return v.Encode()
+}
+
+// UnmarshalBencode sets *v to the value encoded in data, which it copies.
+func (v *Value) UnmarshalBencode(data []byte) error {
+	if v == nil {
+		return errors.New("bencode: UnmarshalBencode on nil pointer")
+	}
+	if err := checkValid(data, false); err != nil {
+		return err
+	}
+	x, _, err := parseValue(bytes.Clone(data), 0)
+	if err != nil {
+		return err
+	}
+	*v = x
+	return nil
+}
+
+// DecodeValue reads a single bencode value from r and returns it as a
+// Value that encodes back to exactly the bytes read. Like Decode, it never
+// consumes bytes from r beyond the end of the value.
+func DecodeValue(r io.Reader) (v Value, err error) {
+	d := decodeState{opts: &decodeOptions{limits: DefaultLimits}}
+	vr := d.readFrom(r)
+	v, err = d.decodeValue()
+	return v, vr.done(err)
+}
+
+// DecodeValue reads the next bencode value from the input and returns it
+// as a Value, following the options set on the Decoder. With SetAliasInput,
+// the strings of the Value share memory with the input.
+func (d *Decoder) DecodeValue() (Value, error) {
+	return d.ds.decodeValue()
+}
+
+// decodeValue reads the next value, checking it the same way as skip, and
+// builds a Value from its encoding.
+func (d *decodeState) decodeValue() (Value, error) {
+	if err := d.begin(); err != nil {
+		return Value{}, err
+	}
+	start := d.pos()
+	raw, err := d.readRaw()
+	if err != nil {
+		return Value{}, err
+	}
+	if !d.alias() {
+		raw = bytes.Clone(raw)
+	}
+	v, _, err := parseValue(raw, 0)
+	return v, rebase(err, start)
+}
+
+// parseValue builds a Value from the encoding at data[i], which has been
+// checked to be well-formed apart from its integers, returning it and the
+// offset of the byte after it. Strings share memory with data.
+func parseValue(data []byte, i int) (Value, int, error) {
+	if i >= len(data) {
+		return Value{}, i, io.ErrUnexpectedEOF
+	}
+	switch c := data[i]; {
+	case c == 'i':
+		end := bytes.IndexByte(data[i:], 'e')
+		if end < 0 {
+			return Value{}, i, io.ErrUnexpectedEOF
+		}
+		text := data[i+1 : i+end : i+end]
+		if !isInteger(text) {
+			return Value{}, i, rebase(badInteger(text), int64(i+1))
+		}
+		return Value{kind: KindInteger, data: text}, i + end + 1, nil
+	case c == 'l':
+		list := []Value{}
+		i++
+		for i < len(data) && data[i] != 'e' {
+			item, next, err := parseValue(data, i)
+			if err != nil {
+				return Value{}, next, err
+			}
+			list = append(list, item)
+			i = next
+		}
+		if i >= len(data) {
+			return Value{}, i, io.ErrUnexpectedEOF
+		}
+		return Value{kind: KindList, list: list}, i + 1, nil
+	case c == 'd':
+		dict := []DictEntry{}
+		i++
+		for i < len(data) && data[i] != 'e' {
+			if data[i] < '0' || data[i] > '9' {
+				return Value{}, i, syntaxError(i, data[i], expectedKey)
+			}
+			key, next, err := parseValue(data, i)
+			if err != nil {
+				return Value{}, next, err
+			}
+			val, next, err := parseValue(data, next)
+			if err != nil {
+				return Value{}, next, err
+			}
+			dict = append(dict, DictEntry{Key: string(key.data), Value: val, size: key.size})
+			i = next
+		}
+		if i >= len(data) {
+			return Value{}, i, io.ErrUnexpectedEOF
+		}
+		return Value{kind: KindDict, dict: dict}, i + 1, nil
+	case c >= '0' && c <= '9':
+		colon := bytes.IndexByte(data[i:], ':')
+		if colon < 0 {
+			return Value{}, i, io.ErrUnexpectedEOF
+		}
+		size := data[i : i+colon : i+colon]
+		n, err := strconv.ParseUint(string(size), 10, 0)
+		if err != nil {
+			return Value{}, i, rebase(badLength(size), int64(i))
+		}
+		start := i + colon + 1
+		if n > uint64(len(data)-start) {
+			return Value{}, len(data), io.ErrUnexpectedEOF
+		}
+		end := start + int(n)
+		var buf [20]byte
+		if bytes.Equal(strconv.AppendUint(buf[:0], n, 10), size) {
+			size = nil
+		}
+		return Value{kind: KindString, data: data[start:end:end], size: size}, end, nil
+	default:
+		return Value{}, i, syntaxError(i, c, expectedValue)
+	}
+}
@@ -0,0 +1,198 @@
+package bencode
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// nonCanonical exercises everything a Value must remember to reproduce
+// its input: unsorted and duplicate keys, leading zeros and "-0".
+var nonCanonical = []string{
+	"i-0e",
+	"i007e",
+	"04:spam",
+	"0:",
+	"le",
+	"de",
+	"d1:bi1e1:ai2e1:bi3ee",
+	"d04:name3:abc4:infod6:lengthi012e5:filesld4:pathl01:aeeeee",
+	"ll01:ai-0eed0:0:ee",
+}
+
+func TestDecodeValueRoundTrip(t *testing.T) {
+	for _, s := range nonCanonical {
+		v, err := DecodeValue(strings.NewReader(s))
+		if err != nil {
+			t.Errorf("DecodeValue(%q): %v", s, err)
+			continue
+		}
+		if got, err := v.Encode(); err != nil || string(got) != s {
+			t.Errorf("DecodeValue(%q).Encode() = %q, %v", s, got, err)
+		}
+
+		d := NewBytesDecoder([]byte(s + s))
+		d.SetAliasInput(true)
+		for i := 0; i < 2; i++ {
+			v, err := d.DecodeValue()
+			if got, _ := v.Encode(); err != nil || string(got) != s {
+				t.Errorf("Decoder.DecodeValue %d of %q = %q, %v", i, s+s, got, err)
+			}
+		}
+		if _, err := d.DecodeValue(); err != io.EOF {
+			t.Errorf("Decoder.DecodeValue at end of input = %v, want io.EOF", err)
+		}
+
+		var f struct {
+			V Value "v"
+		}
+		in := "d1:v" + s + "e"
+		if err := Unmarshal(strings.NewReader(in), &f); err != nil {
+			t.Errorf("Unmarshal(%q): %v", in, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := Marshal(&buf, f); err != nil || buf.String() != in {
+			t.Errorf("Marshal of Value field = %q, %v, want %q", buf.String(), err, in)
+		}
+	}
+}
+
+func TestValueAccessors(t *testing.T) {
+	v, err := DecodeValue(strings.NewReader("d4:infod6:lengthi170917888e4:name5:a.iso6:pieces3:\x00\x01\x02e8:announce3:url5:tiersll1:ael1:bee1:xi1e1:xi2ee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Kind() != KindDict || v.Len() != 5 {
+		t.Fatalf("Kind, Len = %v, %d", v.Kind(), v.Len())
+	}
+	info := v.Get("info")
+	if n, err := info.Get("length").Int64(); err != nil || n != 170917888 {
+		t.Errorf("length = %d, %v", n, err)
+	}
+	if s := info.Get("name").String(); s != "a.iso" {
+		t.Errorf("name = %q", s)
+	}
+	if b := info.Get("pieces").Bytes(); !bytes.Equal(b, []byte{0, 1, 2}) || info.Get("pieces").Len() != 3 {
+		t.Errorf("pieces = %q", b)
+	}
+	if s := v.Get("tiers").Index(1).Index(0).String(); s != "b" {
+		t.Errorf("tiers[1][0] = %q", s)
+	}
+	if n := v.Get("x").Number(); n != "2" {
+		t.Errorf("duplicate key x = %q, want the last value", n)
+	}
+	if keys := v.Entries(); keys[0].Key != "info" || keys[1].Key != "announce" {
+		t.Errorf("Entries out of order: %q, %q", keys[0].Key, keys[1].Key)
+	}
+
+	// Missing values are invalid, however they are reached.
+	for _, m := range []Value{v.Get("nope"), v.Index(0), v.Get("tiers").Index(2), v.Get("tiers").Index(-1), v.Get("nope").Get("x")} {
+		if m.Kind() != KindInvalid || m.Len() != 0 || m.Bytes() != nil || m.Number() != "" {
+			t.Errorf("missing value = %#v", m)
+		}
+		if _, err := m.Int64(); err == nil {
+			t.Error("Int64 of a missing value succeeded")
+		}
+	}
+	if s := v.String(); s != "<dictionary Value>" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestValueEdit(t *testing.T) {
+	const info = "d6:lengthi012e4:name1:ae"
+	in := "d8:announce3:old4:info" + info + "7:comment0:e"
+	v, err := DecodeValue(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := v
+
+	v.Set("announce", StringValue("new"))
+	v.Set("created by", StringValue("me"))
+	v.Set("a", IntValue(-1))
+	v.Delete("comment")
+	tiers := ListValue(ListValue(StringValue("t1")))
+	tiers.Append(ListValue(StringValue("t2")))
+	v.Set("announce-list", tiers)
+
+	want := "d1:ai-1e8:announce3:new13:announce-listll2:t1el2:t2ee10:created by2:me4:info" + info + "e"
+	got, err := v.Encode()
+	if err != nil || string(got) != want {
+		t.Fatalf("Encode() = %q, %v, want %q", got, err, want)
+	}
+	if sha1.Sum(mustEncode(t, v.Get("info"))) != sha1.Sum([]byte(info)) {
+		t.Error("editing the torrent changed the info hash")
+	}
+	if got := mustEncode(t, orig); string(got) != in {
+		t.Errorf("the original Value changed to %q", got)
+	}
+
+	v.Set("announce", Value{})
+	if got := mustEncode(t, v); strings.Contains(string(got), "announce3") {
+		t.Errorf("invalid dictionary value was encoded: %q", got)
+	}
+	if _, err := ListValue(Value{}).Encode(); err == nil {
+		t.Error("Encode of a list holding an invalid Value succeeded")
+	}
+
+	var f struct {
+		A Value "a"
+		B Value "b"
+	}
+	f.B = DictValue(DictEntry{Key: "z", Value: IntValue(1)}, DictEntry{Key: "y", Value: BytesValue(nil)})
+	var buf bytes.Buffer
+	if err := Marshal(&buf, f); err != nil || buf.String() != "d1:bd1:zi1e1:y0:ee" {
+		t.Errorf("Marshal = %q, %v", buf.String(), err)
+	}
+	enc := NewEncoder(&buf)
+	enc.SetCanonical(true)
+	if err := enc.Encode(f); err == nil {
+		t.Error("canonical Encoder accepted unsorted Value keys")
+	}
+}
+
+func mustEncode(t *testing.T, v Value) []byte {
+	t.Helper()
+	b, err := v.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeValueErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{"i1.5e", &SyntaxError{2, '.', "digit or 'e'"}},
+		{"li1ei1.5ee", &SyntaxError{6, '.', "digit or 'e'"}},
+		{"d1:ai1e", io.ErrUnexpectedEOF},
+		{"di1ei2ee", &SyntaxError{1, 'i', expectedKey}},
+		{"", io.EOF},
+	}
+	for _, tt := range tests {
+		_, err := DecodeValue(strings.NewReader(tt.in))
+		if se, ok := tt.err.(*SyntaxError); ok {
+			checkSyntaxError(t, "DecodeValue("+tt.in+")", err, *se)
+		} else if !errors.Is(err, tt.err) {
+			t.Errorf("DecodeValue(%q) = %v, want %v", tt.in, err, tt.err)
+		}
+	}
+
+	d := NewDecoder(strings.NewReader("d1:bi1e1:ai2ee"))
+	d.SetStrict(true)
+	if _, err := d.DecodeValue(); err == nil {
+		t.Error("strict Decoder.DecodeValue accepted unsorted keys")
+	}
+
+	var v Value
+	if err := v.UnmarshalBencode([]byte("i1ei2e")); err == nil {
+		t.Error("UnmarshalBencode accepted trailing data")
+	}
+}
@@ -0,0 +1,227 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// A StreamWriter writes bencode values piece by piece, so that a list or
+// dictionary of any size can be produced without holding it in memory:
+//
+//	sw := bencode.NewStreamWriter(w)
+//	sw.BeginDict()
+//	sw.Key("files")
+//	sw.BeginDict()
+//	for _, t := range torrents {
+//		sw.Key(string(t.InfoHash[:]))
+//		sw.Encode(t.Stats)
+//	}
+//	sw.End()
+//	sw.End()
+//	err := sw.Close()
+//
+// The StreamWriter checks that the calls describe well-formed values: that
+// each dictionary entry is a Key followed by one value, and that each End
+// closes an open list or dictionary. The first error is returned by every
+// later call, and nothing more is written.
+//
+// Output is buffered; Flush or Close writes it to the underlying writer.
+type StreamWriter struct {
+	w      io.Writer
+	e      encodeState
+	frames []writerFrame
+	err    error
+}
+
+// A writerFrame is an open list or dictionary.
+type writerFrame struct {
+	dict    bool
+	n       int    // values written, counting dictionary keys
+	prevKey []byte // the last dictionary key, in canonical mode
+}
+
+// streamWriterBufSize is the amount of output buffered before it is
+// written to the underlying writer.
+const streamWriterBufSize = 4096
+
+// NewStreamWriter returns a new StreamWriter that writes to w.
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: w}
+}
+
+// SetCanonical controls whether the StreamWriter refuses to produce output
+// that is not in canonical form. Dictionary keys must then be written in
+// strictly ascending order, and values written with Encode must be
+// canonical, as for Encoder.SetCanonical.
+func (sw *StreamWriter) SetCanonical(on bool) {
+	sw.e.canonical = on
+}
+
+// BeginDict starts a dictionary, to be followed by its entries, each a
+// Key and a value, and an End.
+func (sw *StreamWriter) BeginDict() error {
+	return sw.begin(true)
+}
+
+// BeginList starts a list, to be followed by its values and an End.
+func (sw *StreamWriter) BeginList() error {
+	return sw.begin(false)
+}
+
+func (sw *StreamWriter) begin(dict bool) error {
+	if err := sw.value(); err != nil {
+		return err
+	}
+	if dict {
+		sw.e.buf = append(sw.e.buf, 'd')
+	} else {
+		sw.e.buf = append(sw.e.buf, 'l')
+	}
+	if n := len(sw.frames); n < cap(sw.frames) {
+		sw.frames = sw.frames[:n+1]
+		sw.frames[n].dict = dict
+		sw.frames[n].n = 0
+	} else {
+		sw.frames = append(sw.frames, writerFrame{dict: dict})
+	}
+	return sw.flushFull()
+}
+
+// Key writes the key of the next entry of the current dictionary.
+func (sw *StreamWriter) Key(key string) error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if len(sw.frames) == 0 || !sw.frames[len(sw.frames)-1].dict {
+		return sw.fail(errors.New("bencode: StreamWriter.Key outside a dictionary"))
+	}
+	top := &sw.frames[len(sw.frames)-1]
+	if top.n%2 != 0 {
+		return sw.fail(fmt.Errorf("bencode: StreamWriter.Key %q after a key with no value", key))
+	}
+	if sw.e.canonical {
+		if top.n > 0 && string(top.prevKey) >= key {
+			return sw.fail(fmt.Errorf("bencode: StreamWriter.Key %q does not sort after %q", key, top.prevKey))
+		}
+		top.prevKey = append(top.prevKey[:0], key...)
+	}
+	top.n++
+	sw.e.writeString(key)
+	return sw.flushFull()
+}
+
+// Int writes an integer.
+func (sw *StreamWriter) Int(i int64) error {
+	if err := sw.value(); err != nil {
+		return err
+	}
+	sw.e.writeInt(i)
+	return sw.flushFull()
+}
+
+// Bytes writes a string holding b.
+func (sw *StreamWriter) Bytes(b []byte) error {
+	if err := sw.value(); err != nil {
+		return err
+	}
+	if len(b) < streamWriterBufSize {
+		sw.e.writeBytes(b)
+		return sw.flushFull()
+	}
+	// Write long strings straight through rather than copying them.
+	sw.e.buf = strconv.AppendInt(sw.e.buf, int64(len(b)), 10)
+	sw.e.buf = append(sw.e.buf, ':')
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	if _, err := sw.w.Write(b); err != nil {
+		return sw.fail(err)
+	}
+	return nil
+}
+
+// Encode writes the encoding of v, following the rules of Marshal.
+func (sw *StreamWriter) Encode(v interface{}) error {
+	if err := sw.value(); err != nil {
+		return err
+	}
+	mark := len(sw.e.buf)
+	if err := sw.e.writeValue(reflect.ValueOf(v)); err != nil {
+		sw.e.buf = sw.e.buf[:mark]
+		return sw.fail(err)
+	}
+	return sw.flushFull()
+}
+
+// End ends the current list or dictionary.
+func (sw *StreamWriter) End() error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if len(sw.frames) == 0 {
+		return sw.fail(errors.New("bencode: StreamWriter.End outside a list or dictionary"))
+	}
+	if top := sw.frames[len(sw.frames)-1]; top.dict && top.n%2 != 0 {
+		return sw.fail(errors.New("bencode: StreamWriter.End after a key with no value"))
+	}
+	sw.frames = sw.frames[:len(sw.frames)-1]
+	sw.e.buf = append(sw.e.buf, 'e')
+	return sw.flushFull()
+}
+
+// Flush writes any buffered output to the underlying writer.
+func (sw *StreamWriter) Flush() error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if len(sw.e.buf) > 0 {
+		_, err := sw.w.Write(sw.e.buf)
+		sw.e.buf = sw.e.buf[:0]
+		if err != nil {
+			return sw.fail(err)
+		}
+	}
+	return nil
+}
+
+// Close checks that every list and dictionary has been ended and flushes
+// the output. It does not close the underlying writer.
+func (sw *StreamWriter) Close() error {
+	if sw.err == nil && len(sw.frames) > 0 {
+		sw.fail(fmt.Errorf("bencode: StreamWriter closed with %d unended lists or dictionaries", len(sw.frames)))
+	}
+	return sw.Flush()
+}
+
+// value prepares to write a value, which must not be in the place of a
+// dictionary key.
+func (sw *StreamWriter) value() error {
+	if sw.err != nil {
+		return sw.err
+	}
+	if len(sw.frames) == 0 {
+		return nil
+	}
+	top := &sw.frames[len(sw.frames)-1]
+	if top.dict && top.n%2 == 0 {
+		return sw.fail(errors.New("bencode: StreamWriter value in a dictionary without a Key"))
+	}
+	top.n++
+	return nil
+}
+
+// flushFull writes the buffered output once there is enough of it.
+func (sw *StreamWriter) flushFull() error {
+	if len(sw.e.buf) >= streamWriterBufSize {
+		return sw.Flush()
+	}
+	return nil
+}
+
+func (sw *StreamWriter) fail(err error) error {
+	sw.err = err
+	return err
+}
@@ -0,0 +1,142 @@
+package bencode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	sw.BeginDict()
+	sw.Key("files")
+	sw.BeginDict()
+	for _, hash := range []string{"aaaa", "bbbb"} {
+		sw.Key(hash)
+		sw.Encode(struct {
+			Complete   int "complete"
+			Downloaded int "downloaded"
+		}{1, 2})
+	}
+	sw.End()
+	sw.Key("flags")
+	sw.BeginList()
+	sw.Int(-3)
+	sw.Bytes([]byte("x"))
+	sw.BeginList()
+	sw.End()
+	sw.End()
+	sw.End()
+	sw.Int(7)
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "d5:filesd4:aaaad8:completei1e10:downloadedi2ee4:bbbbd8:completei1e10:downloadedi2eee5:flagsli-3e1:xleeei7e"
+	if buf.String() != want {
+		t.Errorf("wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStreamWriterLarge(t *testing.T) {
+	// Output is written as it is produced, not held until Close.
+	var w countingWriter
+	sw := NewStreamWriter(&w)
+	sw.BeginList()
+	for i := 0; i < 100000; i++ {
+		sw.Int(int64(i))
+	}
+	if w.writes == 0 {
+		t.Error("nothing written before the list was ended")
+	}
+	long := bytes.Repeat([]byte("z"), 3*streamWriterBufSize)
+	if err := sw.Bytes(long); err != nil {
+		t.Fatal(err)
+	}
+	sw.End()
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var v Value
+	if err := v.UnmarshalBencode(w.Bytes()); err != nil || v.Len() != 100001 || !bytes.Equal(v.Index(100000).Bytes(), long) {
+		t.Errorf("decoding the output = %d values, %v", v.Len(), err)
+	}
+}
+
+func TestStreamWriterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		calls  func(sw *StreamWriter) error
+		output string
+	}{
+		{"End outside", func(sw *StreamWriter) error { return sw.End() }, ""},
+		{"Key outside", func(sw *StreamWriter) error { return sw.Key("a") }, ""},
+		{"Key in list", func(sw *StreamWriter) error { sw.BeginList(); return sw.Key("a") }, ""},
+		{"value without key", func(sw *StreamWriter) error { sw.BeginDict(); return sw.Int(1) }, ""},
+		{"two keys", func(sw *StreamWriter) error { sw.BeginDict(); sw.Key("a"); return sw.Key("b") }, ""},
+		{"End after key", func(sw *StreamWriter) error { sw.BeginDict(); sw.Key("a"); return sw.End() }, ""},
+		{"unended", func(sw *StreamWriter) error { sw.BeginList(); sw.BeginDict(); sw.End(); return sw.Close() }, ""},
+		{"Encode error", func(sw *StreamWriter) error { sw.Int(1); return sw.Encode(make(chan int)) }, ""},
+		{"sticky", func(sw *StreamWriter) error { sw.Int(1); sw.End(); return sw.Int(2) }, ""},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		sw := NewStreamWriter(&buf)
+		if err := tt.calls(sw); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+		if err := sw.Close(); err == nil {
+			t.Errorf("%s: Close after an error succeeded", tt.name)
+		}
+		if buf.String() != tt.output {
+			t.Errorf("%s: wrote %q", tt.name, buf.String())
+		}
+	}
+}
+
+func TestStreamWriterCanonical(t *testing.T) {
+	write := func(canonical bool, keys ...string) error {
+		sw := NewStreamWriter(new(bytes.Buffer))
+		sw.SetCanonical(canonical)
+		sw.BeginDict()
+		for _, k := range keys {
+			sw.Key(k)
+			sw.BeginDict()
+			sw.Key("z")
+			sw.Int(0)
+			sw.Key("y") // out of order in a nested dictionary
+			sw.Int(0)
+			sw.End()
+		}
+		sw.End()
+		return sw.Close()
+	}
+	if err := write(false, "b", "a", "a"); err != nil {
+		t.Errorf("non-canonical StreamWriter rejected keys: %v", err)
+	}
+	if err := write(true); err != nil {
+		t.Errorf("canonical StreamWriter rejected an empty dictionary: %v", err)
+	}
+	err := write(true, "a")
+	if err == nil || !strings.Contains(err.Error(), `"y" does not sort after "z"`) {
+		t.Errorf("canonical StreamWriter error = %v", err)
+	}
+
+	sw := NewStreamWriter(new(bytes.Buffer))
+	sw.SetCanonical(true)
+	for _, k := range []string{"", "a", "ab", "b"} {
+		sw.BeginDict()
+		sw.Key(k)
+		sw.Int(1)
+		sw.End()
+	}
+	sw.BeginDict()
+	sw.Key("a")
+	err = sw.Encode(RawMessage("d1:bi1e1:ai1ee"))
+	if err == nil {
+		t.Error("canonical StreamWriter encoded a non-canonical RawMessage")
+	}
+	if cerr := sw.Close(); cerr != err {
+		t.Errorf("Close = %v, want %v", cerr, err)
+	}
+}